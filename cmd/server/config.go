@@ -0,0 +1,34 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"pr-reviewer/internal/config"
+)
+
+// runConfig implements `server config print [flags]`, which prints the
+// effective configuration as a YAML config file with secrets redacted. It
+// accepts the same flags as the server.
+func runConfig(args []string) {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: server config print [flags]")
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	if err := cfg.WriteRedacted(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "config print: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -2,7 +2,11 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -11,48 +15,156 @@ import (
 
 	"pr-reviewer/internal/config"
 	"pr-reviewer/internal/handlers"
+	"pr-reviewer/internal/logging"
+	"pr-reviewer/internal/metrics"
+	"pr-reviewer/internal/scheduler"
 	"pr-reviewer/internal/service"
 	"pr-reviewer/internal/store"
+	"pr-reviewer/internal/tracing"
 
 	_ "github.com/lib/pq"
 )
 
 func main() {
-	cfg := config.Load()
+	if len(os.Args) > 1 && os.Args[1] == "sync-teams" {
+		runSyncTeams(os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		runConfig(os.Args[2:])
+		return
+	}
+
+	cfg, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+
+	if err := logging.Init(cfg.LogLevel); err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
 
-	dbStore, err := store.NewPostgresStore(cfg.GetDBConnectionString())
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.TracesExporter)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		slog.Error("Failed to initialize tracing", "error", err)
+		os.Exit(1)
+	}
+
+	connStr, err := cfg.GetDBConnectionString()
+	if err != nil {
+		slog.Error("Invalid database configuration", "error", err)
+		os.Exit(1)
+	}
+
+	dbStore, err := store.NewPostgresStore(context.Background(), connStr, storeOptions(cfg))
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
 	}
 	defer dbStore.Close()
 
-	svc := service.NewService(dbStore)
+	instrumented := store.Instrument(dbStore, func(ctx context.Context, method string, duration time.Duration, err error) {
+		metrics.ObserveQuery(ctx, method, duration, err)
+		logging.LogQuery(ctx, method, duration, err, store.IsDatabaseError(err))
+	})
+	metrics.RegisterTeamStats(instrumented.GetTeamStats)
 
-	router := handlers.NewRouter(svc)
+	svc := service.NewService(instrumented, cfg.AssignmentSeed, service.AssignmentObserverFunc(metrics.ObserveAssignment))
+	slog.Info("Reviewer assignment seed", "seed", svc.Seed())
+
+	reload, err := newReloader(os.Args[1:], cfg, svc)
+	if err != nil {
+		slog.Error("Invalid configuration", "error", err)
+		os.Exit(1)
+	}
+
+	readiness := handlers.NewReadiness(dbStore, cfg.ReadinessTimeout)
+	router := handlers.NewRouter(svc, handlers.RouterConfig{
+		Settings:   reload.settings,
+		Readiness:  readiness,
+		AdminToken: cfg.AdminToken,
+		Reload:     reload.Reload,
+	})
+
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+	if cfg.EscalationInterval > 0 {
+		go scheduler.New(svc, cfg.EscalationInterval).Run(schedulerCtx)
+	}
+
+	// Request contexts derive from baseCtx, so cancelling it aborts the
+	// queries of requests still running when the shutdown timeout expires.
+	baseCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
 
 	server := &http.Server{
-		Addr:    ":" + cfg.ServerPort,
-		Handler: router,
+		Addr:              ":" + cfg.ServerPort,
+		Handler:           router,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		ReadTimeout:       cfg.ReadTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		ErrorLog:          slog.NewLogLogger(slog.Default().Handler(), slog.LevelWarn),
+		BaseContext:       func(net.Listener) context.Context { return baseCtx },
 	}
 
 	go func() {
-		log.Printf("Server starting on port %s", cfg.ServerPort)
+		slog.Info("Server starting", "port", cfg.ServerPort)
 		if err := server.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Server failed to start: %v", err)
+			slog.Error("Server failed to start", "error", err)
+			os.Exit(1)
+		}
+	}()
+
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	go func() {
+		for range hangup {
+			slog.Info("Reloading configuration on SIGHUP")
+			reload.Reload(context.Background())
 		}
 	}()
 
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	signal.Stop(hangup)
+	slog.Info("Draining before shutdown", "delay", cfg.DrainDelay.String())
+	readiness.StartDraining()
+	time.Sleep(cfg.DrainDelay)
+
+	slog.Info("Shutting down server")
+	stopScheduler()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		log.Fatalf("Server forced to shutdown: %v", err)
+		slog.Error("Server forced to shutdown", "error", err)
+		cancelRequests()
+		server.Close()
+		os.Exit(1)
+	}
+
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("Failed to flush traces", "error", err)
 	}
 
-	log.Println("Server exited")
+	slog.Info("Server exited")
+}
+
+func storeOptions(cfg *config.Config) store.Options {
+	return store.Options{
+		QueryTimeout:    cfg.QueryTimeout,
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+		ConnMaxIdleTime: cfg.DBConnMaxIdleTime,
+		ConnectTimeout:  cfg.DBConnectTimeout,
+	}
 }
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"pr-reviewer/internal/config"
+	"pr-reviewer/internal/handlers"
+	"pr-reviewer/internal/logging"
+	"pr-reviewer/internal/service"
+)
+
+// reloader re-reads the configuration on SIGHUP or POST /admin/reload and
+// swaps in the settings that can change without a restart.
+type reloader struct {
+	mu       sync.Mutex
+	args     []string
+	current  *config.Config
+	service  *service.Service
+	settings *handlers.SettingsValue
+}
+
+func newReloader(args []string, cfg *config.Config, svc *service.Service) (*reloader, error) {
+	r := &reloader{
+		args:     args,
+		current:  cfg,
+		service:  svc,
+		settings: handlers.NewSettingsValue(httpSettings(cfg)),
+	}
+	if err := r.apply(cfg); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload loads and validates the configuration as at startup. An invalid
+// configuration is rejected as a whole and the current one stays in effect.
+func (r *reloader) Reload(ctx context.Context) ([]config.Change, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	logger := logging.FromContext(ctx)
+
+	next, err := config.Load(r.args)
+	if err != nil {
+		logger.Error("Configuration reload rejected", "error", err)
+		return nil, err
+	}
+	// Without ASSIGNMENT_SEED the seed is drawn at startup, so a fresh load
+	// would always report it as changed.
+	next.AssignmentSeed = r.current.AssignmentSeed
+
+	// The merged configuration pairs the new reloadable settings with the
+	// running ones and is validated again before anything is applied.
+	merged, changes := r.current.Reload(next)
+	if err := merged.Validate(); err != nil {
+		logger.Error("Configuration reload rejected", "error", err)
+		return nil, err
+	}
+	if err := r.apply(merged); err != nil {
+		logger.Error("Configuration reload rejected", "error", err)
+		return nil, err
+	}
+	r.current = merged
+
+	for _, change := range changes {
+		if change.Applied {
+			logger.Info("Setting reloaded", "setting", change.Setting, "old", change.Old, "new", change.New)
+		} else {
+			logger.Warn("Setting changed but needs a restart", "setting", change.Setting, "old", change.Old, "new", change.New)
+		}
+	}
+	logger.Info("Configuration reloaded", "changes", len(changes))
+	return changes, nil
+}
+
+func (r *reloader) apply(cfg *config.Config) error {
+	if err := logging.SetLevel(cfg.LogLevel); err != nil {
+		return err
+	}
+	r.service.Configure(service.Settings{
+		DefaultMode:        cfg.AssignmentMode,
+		PreferWorkingHours: cfg.PreferWorkingHours,
+		SkillsAssignment:   cfg.FeatureSkillsAssignment,
+		Escalation:         cfg.FeatureEscalation,
+	})
+	r.settings.Store(httpSettings(cfg))
+	return nil
+}
+
+func httpSettings(cfg *config.Config) handlers.Settings {
+	return handlers.Settings{
+		RequestTimeout: cfg.RequestTimeout,
+		MaxBodyBytes:   cfg.MaxBodyBytes,
+		RateLimits: map[string]handlers.RateLimit{
+			handlers.RouteGroupRead:  {Rate: cfg.RateLimitReadRPS, Burst: cfg.RateLimitReadBurst},
+			handlers.RouteGroupWrite: {Rate: cfg.RateLimitWriteRPS, Burst: cfg.RateLimitWriteBurst},
+		},
+		RateLimitBy:       cfg.RateLimitBy,
+		TrustForwardedFor: cfg.RateLimitTrustForwardedFor,
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/config"
+	"pr-reviewer/internal/service"
+)
+
+func newTestReloader(t *testing.T, file string) (*reloader, *service.Service, string) {
+	t.Helper()
+	for _, env := range []string{"CONFIG_FILE", "LOG_LEVEL", "ASSIGNMENT_MODE", "FEATURE_SKILLS_ASSIGNMENT", "REQUEST_TIMEOUT"} {
+		t.Setenv(env, "")
+	}
+	path := filepath.Join(t.TempDir(), "config.yml")
+	writeConfig(t, path, file)
+
+	args := []string{"-config", path, "-db-password", "secret"}
+	cfg, err := config.Load(args)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	svc := service.NewService(nil, 1, nil)
+	r, err := newReloader(args, cfg, svc)
+	if err != nil {
+		t.Fatalf("newReloader: %v", err)
+	}
+	return r, svc, path
+}
+
+func writeConfig(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReloadAppliesReloadableSettings(t *testing.T) {
+	r, svc, path := newTestReloader(t, "assignment_mode: random\nrequest_timeout: 5s\n")
+
+	writeConfig(t, path, "assignment_mode: skills\nrequest_timeout: 7s\nserver_port: \"9090\"\n")
+	changes, err := r.Reload(context.Background())
+	if err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	if got := svc.Settings().DefaultMode; got != "skills" {
+		t.Errorf("DefaultMode = %q, want skills", got)
+	}
+	if got := r.settings.Load().RequestTimeout; got != 7*time.Second {
+		t.Errorf("RequestTimeout = %v, want 7s", got)
+	}
+	if r.current.ServerPort != "8080" {
+		t.Errorf("ServerPort = %q, want the running 8080", r.current.ServerPort)
+	}
+
+	applied := make(map[string]bool)
+	for _, change := range changes {
+		applied[change.Setting] = change.Applied
+	}
+	want := map[string]bool{"ASSIGNMENT_MODE": true, "REQUEST_TIMEOUT": true, "SERVER_PORT": false}
+	if len(applied) != len(want) {
+		t.Fatalf("changes = %+v, want %v", changes, want)
+	}
+	for setting, ok := range want {
+		if got, found := applied[setting]; !found || got != ok {
+			t.Errorf("%s applied = %v (reported %v), want %v", setting, got, found, ok)
+		}
+	}
+}
+
+func TestReloadRejectsInvalidConfiguration(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+	}{
+		{name: "unknown mode", file: "assignment_mode: fastest\nrequest_timeout: 7s\n"},
+		{name: "bad log level", file: "log_level: loud\nassignment_mode: skills\n"},
+		{name: "skills without feature", file: "assignment_mode: skills\nfeature_skills_assignment: false\n"},
+		{name: "negative timeout", file: "request_timeout: -1s\n"},
+		{name: "malformed file", file: "assignment_mode: [skills\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, svc, path := newTestReloader(t, "assignment_mode: random\nrequest_timeout: 5s\n")
+			before, settings := r.current, svc.Settings()
+
+			writeConfig(t, path, tt.file)
+			if _, err := r.Reload(context.Background()); err == nil {
+				t.Fatal("Reload accepted an invalid configuration")
+			}
+
+			if r.current != before {
+				t.Error("current configuration was replaced")
+			}
+			if got := svc.Settings(); got != settings {
+				t.Errorf("service settings = %+v, want %+v", got, settings)
+			}
+			if got := r.settings.Load().RequestTimeout; got != 5*time.Second {
+				t.Errorf("RequestTimeout = %v, want 5s", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"pr-reviewer/internal/config"
+	"pr-reviewer/internal/logging"
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/service"
+	"pr-reviewer/internal/store"
+
+	"gopkg.in/yaml.v3"
+)
+
+// runSyncTeams implements
+// `server sync-teams -file teams.yaml [-dry-run] [-- server flags]`. The
+// file has the same shape as the PUT /team/sync body and the resulting diff
+// is printed as JSON. Flags after -- configure the database as for the
+// server.
+func runSyncTeams(args []string) {
+	flags := flag.NewFlagSet("sync-teams", flag.ExitOnError)
+	file := flags.String("file", "", "YAML or JSON file with the complete set of teams")
+	dryRun := flags.Bool("dry-run", false, "print the diff without applying it")
+	flags.Parse(args)
+
+	if *file == "" {
+		fmt.Fprintln(os.Stderr, "usage: server sync-teams -file teams.yaml [-dry-run] [-- server flags]")
+		os.Exit(2)
+	}
+
+	cfg, err := config.Load(flags.Args())
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration:\n%v\n", err)
+		os.Exit(2)
+	}
+	if err := logging.Init(cfg.LogLevel); err != nil {
+		slog.Error("Invalid logging configuration", "error", err)
+		os.Exit(1)
+	}
+
+	teams, err := readTeamsFile(*file)
+	if err != nil {
+		slog.Error("Failed to read teams file", "file", *file, "error", err)
+		os.Exit(1)
+	}
+
+	// A whole-directory sync runs in one transaction, so it is not bound by
+	// the per-call QUERY_TIMEOUT meant for API requests.
+	connStr, err := cfg.GetDBConnectionString()
+	if err != nil {
+		slog.Error("Invalid database configuration", "error", err)
+		os.Exit(1)
+	}
+
+	opts := storeOptions(cfg)
+	opts.QueryTimeout = 0
+	dbStore, err := store.NewPostgresStore(context.Background(), connStr, opts)
+	if err != nil {
+		slog.Error("Failed to connect to database", "error", err)
+		os.Exit(1)
+	}
+	defer dbStore.Close()
+
+	svc := service.NewService(dbStore, cfg.AssignmentSeed, nil)
+
+	diff, err := svc.SyncTeams(context.Background(), teams, *dryRun)
+	if err != nil {
+		slog.Error("Team sync failed", "error", err)
+		dbStore.Close()
+		os.Exit(1)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(map[string]interface{}{
+		"dry_run": *dryRun,
+		"diff":    diff,
+	})
+}
+
+// readTeamsFile parses the directory file. YAML is a superset of JSON, and
+// the document is converted to JSON so the file uses the API field names.
+func readTeamsFile(path string) ([]models.Team, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document interface{}
+	if err := yaml.Unmarshal(raw, &document); err != nil {
+		return nil, err
+	}
+
+	asJSON, err := json.Marshal(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var payload struct {
+		Teams []models.Team `json:"teams"`
+	}
+	if err := json.Unmarshal(asJSON, &payload); err != nil {
+		return nil, err
+	}
+
+	return payload.Teams, nil
+}
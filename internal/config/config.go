@@ -1,8 +1,14 @@
 package config
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"net"
+	"net/url"
 	"os"
+	"strconv"
+	"time"
 )
 
 type Config struct {
@@ -12,27 +18,312 @@ type Config struct {
 	DBPassword string
 	DBName     string
 	ServerPort string
+
+	// DatabaseURL, when set, is a complete postgres:// URL used instead of
+	// the DB_HOST..DB_NAME parts. The TLS and statement timeout options
+	// below override the matching URL parameters when they are set.
+	DatabaseURL string
+
+	// DBSSLMode is a libpq sslmode (disable, require, verify-ca or
+	// verify-full). The certificate options are file paths.
+	DBSSLMode     string
+	DBSSLRootCert string
+	DBSSLCert     string
+	DBSSLKey      string
+
+	// DBStatementTimeout is sent as the session statement_timeout so the
+	// server itself aborts runaway statements. Zero keeps the server default.
+	DBStatementTimeout time.Duration
+
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+	DBConnMaxIdleTime time.Duration
+
+	// DBConnectTimeout is how long startup retries an unreachable database.
+	// Zero tries once.
+	DBConnectTimeout time.Duration
+
+	// AssignmentSeed seeds reviewer selection. Without ASSIGNMENT_SEED a
+	// time-based seed is chosen at startup and logged so runs can be replayed.
+	AssignmentSeed int64
+
+	// LogLevel is debug, info, warn or error.
+	LogLevel string
+
+	// AssignmentMode is the assignment mode of requests that do not pick
+	// one: random, skills, or empty to use skills only when labels are given.
+	// PreferWorkingHours applies prefer_working_hours to every request.
+	AssignmentMode     string
+	PreferWorkingHours bool
+
+	// FeatureSkillsAssignment enables the skills assignment mode and
+	// FeatureEscalation the background escalation of stale reviews.
+	FeatureSkillsAssignment bool
+	FeatureEscalation       bool
+
+	// AdminToken enables POST /admin/reload for requests bearing it.
+	AdminToken string
+
+	// TracesExporter is "otlp" to export spans or "none". The OTLP endpoint
+	// and headers come from the standard OTEL_EXPORTER_OTLP_* variables.
+	TracesExporter string
+
+	// RequestTimeout is the deadline of every HTTP request and QueryTimeout
+	// the deadline of every store call within it. Zero disables either.
+	RequestTimeout time.Duration
+	QueryTimeout   time.Duration
+
+	// Server timeouts of the HTTP server. WriteTimeout must exceed
+	// RequestTimeout so that timed out requests can still be answered.
+	ReadHeaderTimeout time.Duration
+	ReadTimeout       time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+
+	// MaxBodyBytes caps JSON request bodies.
+	MaxBodyBytes int64
+
+	// Per-client token buckets of the read (GET) and write route groups,
+	// in requests per second. A zero rate disables the limit. Clients are
+	// told apart by RateLimitBy: "ip" or "token" (the Authorization header).
+	// Tokens are not checked, so "token" needs a gateway that rejects
+	// unknown ones; otherwise a client evades the limit by changing tokens.
+	RateLimitReadRPS           float64
+	RateLimitReadBurst         int
+	RateLimitWriteRPS          float64
+	RateLimitWriteBurst        int
+	RateLimitBy                string
+	RateLimitTrustForwardedFor bool
+
+	// ShutdownTimeout is how long in-flight requests may finish on shutdown
+	// before their queries are cancelled. DrainDelay is how long /readyz
+	// fails before shutdown starts, so load balancers stop sending traffic.
+	ShutdownTimeout time.Duration
+	DrainDelay      time.Duration
+
+	// ReadinessTimeout bounds the database checks of /readyz.
+	ReadinessTimeout time.Duration
+
+	// EscalationInterval is how often stale reviews are escalated. Zero
+	// disables the background scheduler.
+	EscalationInterval time.Duration
 }
 
-func Load() *Config {
+// Load builds the configuration from, in increasing priority, the
+// defaults, the YAML file named by -config or CONFIG_FILE, the environment
+// and the command-line flags in args, and validates the result.
+func Load(args []string) (*Config, error) {
+	flagValues, configFile, err := parseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+	if configFile == "" {
+		configFile = os.Getenv("CONFIG_FILE")
+	}
+
+	cfg := defaults()
+	if configFile != "" {
+		if err := cfg.loadFile(configFile); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.loadEnv(); err != nil {
+		return nil, err
+	}
+	for _, s := range cfg.settings() {
+		if value, ok := flagValues[s.env]; ok {
+			if err := s.value.Set(value); err != nil {
+				return nil, fmt.Errorf("-%s: %w", s.flagName(), err)
+			}
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+func defaults() *Config {
 	return &Config{
-		DBHost:     getEnv("DB_HOST", "localhost"),
-		DBPort:     getEnv("DB_PORT", "5432"),
-		DBUser:     getEnv("DB_USER", "postgres"),
-		DBPassword: getEnv("DB_PASSWORD", "password"),
-		DBName:     getEnv("DB_NAME", "pr_reviewer"),
-		ServerPort: getEnv("SERVER_PORT", "8080"),
+		DBHost:     "localhost",
+		DBPort:     "5432",
+		DBUser:     "postgres",
+		DBName:     "pr_reviewer",
+		ServerPort: "8080",
+
+		DBMaxOpenConns:    25,
+		DBMaxIdleConns:    10,
+		DBConnMaxLifetime: 30 * time.Minute,
+		DBConnMaxIdleTime: 5 * time.Minute,
+		DBConnectTimeout:  time.Minute,
+
+		LogLevel:       "info",
+		TracesExporter: "none",
+
+		FeatureSkillsAssignment: true,
+		FeatureEscalation:       true,
+
+		AssignmentSeed: time.Now().UnixNano(),
+
+		RequestTimeout: 30 * time.Second,
+		QueryTimeout:   10 * time.Second,
+
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       30 * time.Second,
+		WriteTimeout:      60 * time.Second,
+		IdleTimeout:       2 * time.Minute,
+
+		MaxBodyBytes: 1 << 20,
+
+		RateLimitReadRPS:    50,
+		RateLimitReadBurst:  100,
+		RateLimitWriteRPS:   10,
+		RateLimitWriteBurst: 20,
+		RateLimitBy:         "ip",
+
+		ShutdownTimeout: 30 * time.Second,
+		DrainDelay:      5 * time.Second,
+
+		ReadinessTimeout: 2 * time.Second,
+
+		EscalationInterval: 5 * time.Minute,
+	}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(name, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: "+format, append([]any{name}, args...)...))
+	}
+
+	if !validPort(c.ServerPort) {
+		invalid("SERVER_PORT", "%q is not a port number", c.ServerPort)
+	}
+	if c.DatabaseURL == "" {
+		if c.DBHost == "" {
+			invalid("DB_HOST", "is required unless DATABASE_URL is set")
+		}
+		if !validPort(c.DBPort) {
+			invalid("DB_PORT", "%q is not a port number", c.DBPort)
+		}
+		if c.DBPassword == "" {
+			invalid("DB_PASSWORD", "is required unless DATABASE_URL is set (or use DB_PASSWORD_FILE)")
+		}
+	}
+	switch c.DBSSLMode {
+	case "", "disable", "require", "verify-ca", "verify-full":
+	default:
+		invalid("DB_SSLMODE", "%q is not one of disable, require, verify-ca, verify-full", c.DBSSLMode)
+	}
+	if _, err := c.GetDBConnectionString(); err != nil {
+		errs = append(errs, err)
+	}
+
+	if c.DBMaxOpenConns < 0 {
+		invalid("DB_MAX_OPEN_CONNS", "must not be negative")
+	}
+	if c.DBMaxIdleConns < 0 {
+		invalid("DB_MAX_IDLE_CONNS", "must not be negative")
+	}
+	if c.DBConnectTimeout < 0 {
+		invalid("DB_CONNECT_TIMEOUT", "must not be negative")
+	}
+
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		invalid("LOG_LEVEL", "%q is not one of debug, info, warn, error", c.LogLevel)
+	}
+	if c.TracesExporter != "none" && c.TracesExporter != "otlp" {
+		invalid("OTEL_TRACES_EXPORTER", "%q is not one of none, otlp", c.TracesExporter)
+	}
+	switch c.AssignmentMode {
+	case "", "random":
+	case "skills":
+		if !c.FeatureSkillsAssignment {
+			invalid("ASSIGNMENT_MODE", "skills requires FEATURE_SKILLS_ASSIGNMENT")
+		}
+	default:
+		invalid("ASSIGNMENT_MODE", "%q is not one of random, skills", c.AssignmentMode)
+	}
+
+	if c.WriteTimeout > 0 && c.RequestTimeout > 0 && c.WriteTimeout <= c.RequestTimeout {
+		invalid("WRITE_TIMEOUT", "must exceed REQUEST_TIMEOUT (%s)", c.RequestTimeout)
+	}
+	if c.MaxBodyBytes < 0 {
+		invalid("MAX_BODY_BYTES", "must not be negative")
+	}
+	if c.RateLimitBy != "ip" && c.RateLimitBy != "token" {
+		invalid("RATE_LIMIT_BY", "%q is not one of ip, token", c.RateLimitBy)
+	}
+
+	for _, s := range c.settings() {
+		switch v := s.value.(type) {
+		case *durationValue:
+			if *v < 0 {
+				invalid(s.env, "must not be negative")
+			}
+		case *float64Value:
+			if *v < 0 {
+				invalid(s.env, "must not be negative")
+			}
+		}
 	}
+
+	return errors.Join(errs...)
+}
+
+func validPort(port string) bool {
+	n, err := strconv.Atoi(port)
+	return err == nil && n > 0 && n <= 65535
 }
 
-func (c *Config) GetDBConnectionString() string {
-	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		c.DBHost, c.DBPort, c.DBUser, c.DBPassword, c.DBName)
+// GetDBConnectionString returns a postgres:// URL built from DATABASE_URL
+// or the DB_* parts, with the TLS and statement timeout options applied.
+// Without DATABASE_URL or DB_SSLMODE TLS stays disabled as before. The
+// session time zone is always UTC: the TIMESTAMP columns hold UTC wall
+// clock times, and NOW() defaults would otherwise follow the server zone.
+func (c *Config) GetDBConnectionString() (string, error) {
+	var u *url.URL
+	if c.DatabaseURL != "" {
+		parsed, err := url.Parse(c.DatabaseURL)
+		if err != nil {
+			return "", fmt.Errorf("invalid DATABASE_URL: %w", err)
+		}
+		if parsed.Scheme != "postgres" && parsed.Scheme != "postgresql" {
+			return "", fmt.Errorf("invalid DATABASE_URL: unsupported scheme %q", parsed.Scheme)
+		}
+		u = parsed
+	} else {
+		u = &url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(c.DBUser, c.DBPassword),
+			Host:   net.JoinHostPort(c.DBHost, c.DBPort),
+			Path:   "/" + c.DBName,
+		}
+	}
+
+	query := u.Query()
+	if c.DatabaseURL == "" && c.DBSSLMode == "" {
+		query.Set("sslmode", "disable")
+	}
+	setParam(query, "sslmode", c.DBSSLMode)
+	setParam(query, "sslrootcert", c.DBSSLRootCert)
+	setParam(query, "sslcert", c.DBSSLCert)
+	setParam(query, "sslkey", c.DBSSLKey)
+	query.Set("timezone", "UTC")
+	if c.DBStatementTimeout > 0 {
+		query.Set("statement_timeout", strconv.FormatInt(c.DBStatementTimeout.Milliseconds(), 10))
+	}
+	u.RawQuery = query.Encode()
+
+	return u.String(), nil
 }
 
-func getEnv(key, defaultValue string) string {
-	if value := os.Getenv(key); value != "" {
-		return value
+func setParam(query url.Values, key, value string) {
+	if value != "" {
+		query.Set(key, value)
 	}
-	return defaultValue
 }
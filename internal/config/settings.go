@@ -0,0 +1,339 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// setting binds a Config field to its environment variable. The config file
+// key is the lowercased variable name (db_host) and the flag the same with
+// dashes (-db-host). Reloadable settings take effect on Reload; the others
+// need a restart.
+type setting struct {
+	env        string
+	value      flag.Value
+	redact     func(string) string
+	reloadable bool
+}
+
+func (s setting) fileKey() string {
+	return strings.ToLower(s.env)
+}
+
+func (s setting) flagName() string {
+	return strings.ReplaceAll(s.fileKey(), "_", "-")
+}
+
+func (c *Config) settings() []setting {
+	return []setting{
+		{env: "SERVER_PORT", value: (*stringValue)(&c.ServerPort)},
+
+		{env: "DATABASE_URL", value: (*stringValue)(&c.DatabaseURL), redact: redactURL},
+		{env: "DB_HOST", value: (*stringValue)(&c.DBHost)},
+		{env: "DB_PORT", value: (*stringValue)(&c.DBPort)},
+		{env: "DB_USER", value: (*stringValue)(&c.DBUser)},
+		{env: "DB_PASSWORD", value: (*stringValue)(&c.DBPassword), redact: redactAll},
+		{env: "DB_NAME", value: (*stringValue)(&c.DBName)},
+		{env: "DB_SSLMODE", value: (*stringValue)(&c.DBSSLMode)},
+		{env: "DB_SSLROOTCERT", value: (*stringValue)(&c.DBSSLRootCert)},
+		{env: "DB_SSLCERT", value: (*stringValue)(&c.DBSSLCert)},
+		{env: "DB_SSLKEY", value: (*stringValue)(&c.DBSSLKey)},
+		{env: "DB_STATEMENT_TIMEOUT", value: (*durationValue)(&c.DBStatementTimeout)},
+		{env: "DB_MAX_OPEN_CONNS", value: (*intValue)(&c.DBMaxOpenConns)},
+		{env: "DB_MAX_IDLE_CONNS", value: (*intValue)(&c.DBMaxIdleConns)},
+		{env: "DB_CONN_MAX_LIFETIME", value: (*durationValue)(&c.DBConnMaxLifetime)},
+		{env: "DB_CONN_MAX_IDLE_TIME", value: (*durationValue)(&c.DBConnMaxIdleTime)},
+		{env: "DB_CONNECT_TIMEOUT", value: (*durationValue)(&c.DBConnectTimeout)},
+
+		{env: "LOG_LEVEL", value: (*stringValue)(&c.LogLevel), reloadable: true},
+		{env: "OTEL_TRACES_EXPORTER", value: (*stringValue)(&c.TracesExporter)},
+
+		{env: "ASSIGNMENT_SEED", value: (*int64Value)(&c.AssignmentSeed)},
+		{env: "ASSIGNMENT_MODE", value: (*stringValue)(&c.AssignmentMode), reloadable: true},
+		{env: "ASSIGNMENT_PREFER_WORKING_HOURS", value: (*boolValue)(&c.PreferWorkingHours), reloadable: true},
+		{env: "FEATURE_SKILLS_ASSIGNMENT", value: (*boolValue)(&c.FeatureSkillsAssignment), reloadable: true},
+		{env: "FEATURE_ESCALATION", value: (*boolValue)(&c.FeatureEscalation), reloadable: true},
+		{env: "ADMIN_TOKEN", value: (*stringValue)(&c.AdminToken), redact: redactAll},
+
+		{env: "REQUEST_TIMEOUT", value: (*durationValue)(&c.RequestTimeout), reloadable: true},
+		{env: "QUERY_TIMEOUT", value: (*durationValue)(&c.QueryTimeout)},
+		{env: "READ_HEADER_TIMEOUT", value: (*durationValue)(&c.ReadHeaderTimeout)},
+		{env: "READ_TIMEOUT", value: (*durationValue)(&c.ReadTimeout)},
+		{env: "WRITE_TIMEOUT", value: (*durationValue)(&c.WriteTimeout)},
+		{env: "IDLE_TIMEOUT", value: (*durationValue)(&c.IdleTimeout)},
+		{env: "MAX_BODY_BYTES", value: (*int64Value)(&c.MaxBodyBytes), reloadable: true},
+
+		{env: "RATE_LIMIT_READ_RPS", value: (*float64Value)(&c.RateLimitReadRPS), reloadable: true},
+		{env: "RATE_LIMIT_READ_BURST", value: (*intValue)(&c.RateLimitReadBurst), reloadable: true},
+		{env: "RATE_LIMIT_WRITE_RPS", value: (*float64Value)(&c.RateLimitWriteRPS), reloadable: true},
+		{env: "RATE_LIMIT_WRITE_BURST", value: (*intValue)(&c.RateLimitWriteBurst), reloadable: true},
+		{env: "RATE_LIMIT_BY", value: (*stringValue)(&c.RateLimitBy), reloadable: true},
+		{env: "RATE_LIMIT_TRUST_FORWARDED_FOR", value: (*boolValue)(&c.RateLimitTrustForwardedFor), reloadable: true},
+
+		{env: "SHUTDOWN_TIMEOUT", value: (*durationValue)(&c.ShutdownTimeout)},
+		{env: "SHUTDOWN_DRAIN_DELAY", value: (*durationValue)(&c.DrainDelay)},
+		{env: "READINESS_TIMEOUT", value: (*durationValue)(&c.ReadinessTimeout)},
+		{env: "ESCALATION_INTERVAL", value: (*durationValue)(&c.EscalationInterval)},
+	}
+}
+
+// parseFlags returns the raw values of the flags present in args, keyed by
+// environment variable name, so they can be applied after the file and the
+// environment.
+func parseFlags(args []string) (map[string]string, string, error) {
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	configFile := flags.String("config", "", "YAML config file (default $CONFIG_FILE)")
+
+	scratch := defaults()
+	byFlag := make(map[string]string)
+	for _, s := range scratch.settings() {
+		flags.Var(s.value, s.flagName(), "overrides "+s.env)
+		byFlag[s.flagName()] = s.env
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return nil, "", err
+	}
+	if flags.NArg() > 0 {
+		return nil, "", fmt.Errorf("unexpected argument %q", flags.Arg(0))
+	}
+
+	values := make(map[string]string)
+	flags.Visit(func(f *flag.Flag) {
+		if env, ok := byFlag[f.Name]; ok {
+			values[env] = f.Value.String()
+		}
+	})
+	return values, *configFile, nil
+}
+
+// loadFile applies a YAML mapping of file keys to scalars. Scalars are taken
+// as written rather than decoded, so an unquoted password such as 0123 or
+// 1.10 reaches the setting as it would from the environment.
+func (c *Config) loadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("config file %s: expected a mapping of settings", path)
+	}
+
+	byKey := make(map[string]setting)
+	for _, s := range c.settings() {
+		byKey[s.fileKey()] = s
+	}
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		key, value := root.Content[i].Value, root.Content[i+1]
+		s, ok := byKey[key]
+		if !ok {
+			return fmt.Errorf("config file %s: unknown setting %q", path, key)
+		}
+		if value.Kind == yaml.AliasNode {
+			value = value.Alias
+		}
+		if value.Kind != yaml.ScalarNode {
+			return fmt.Errorf("config file %s: %s must be a single value", path, key)
+		}
+		if value.Tag == "!!null" {
+			continue
+		}
+		if err := s.value.Set(value.Value); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, key, err)
+		}
+	}
+	return nil
+}
+
+// loadEnv applies the environment. Each variable X may instead be given as
+// X_FILE naming a file with the value, as with Docker secrets.
+func (c *Config) loadEnv() error {
+	for _, s := range c.settings() {
+		value := os.Getenv(s.env)
+		if file := os.Getenv(s.env + "_FILE"); file != "" {
+			if value != "" {
+				return fmt.Errorf("%s and %s_FILE are both set", s.env, s.env)
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("%s_FILE: %w", s.env, err)
+			}
+			value = strings.TrimRight(string(data), "\r\n")
+		}
+		if value == "" {
+			continue
+		}
+		if err := s.value.Set(value); err != nil {
+			return fmt.Errorf("%s: %w", s.env, err)
+		}
+	}
+	return nil
+}
+
+// Change is a setting that differs between two configurations, with
+// secrets redacted. Applied is false for settings that need a restart.
+type Change struct {
+	Setting string `json:"setting"`
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Applied bool   `json:"applied"`
+}
+
+// Reload returns a copy of c with the reloadable settings taken from next,
+// and every setting that differs between the two.
+func (c *Config) Reload(next *Config) (*Config, []Change) {
+	merged := *c
+	mergedSettings := merged.settings()
+	nextSettings := next.settings()
+
+	changes := make([]Change, 0)
+	for i, s := range mergedSettings {
+		oldValue, newValue := s.value.String(), nextSettings[i].value.String()
+		if oldValue == newValue {
+			continue
+		}
+		if s.reloadable {
+			s.value.Set(newValue)
+		}
+		if s.redact != nil {
+			oldValue, newValue = s.redact(oldValue), s.redact(newValue)
+		}
+		changes = append(changes, Change{Setting: s.env, Old: oldValue, New: newValue, Applied: s.reloadable})
+	}
+	return &merged, changes
+}
+
+// WriteRedacted writes the configuration as a YAML config file with
+// passwords redacted.
+func (c *Config) WriteRedacted(w io.Writer) error {
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range c.settings() {
+		value := s.value.String()
+		if s.redact != nil && value != "" {
+			value = s.redact(value)
+		}
+		doc.Content = append(doc.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: s.fileKey()},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: value, Style: scalarStyle(s.value)},
+		)
+	}
+
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+func scalarStyle(value flag.Value) yaml.Style {
+	if _, ok := value.(*stringValue); ok {
+		return yaml.DoubleQuotedStyle
+	}
+	return 0
+}
+
+func redactAll(string) string {
+	return "REDACTED"
+}
+
+func redactURL(value string) string {
+	u, err := url.Parse(value)
+	if err != nil {
+		return redactAll(value)
+	}
+	return u.Redacted()
+}
+
+type stringValue string
+
+func (v *stringValue) Set(s string) error {
+	*v = stringValue(s)
+	return nil
+}
+
+func (v *stringValue) String() string { return string(*v) }
+
+type boolValue bool
+
+func (v *boolValue) Set(s string) error {
+	parsed, err := strconv.ParseBool(s)
+	if err != nil {
+		return fmt.Errorf("%q is not true or false", s)
+	}
+	*v = boolValue(parsed)
+	return nil
+}
+
+func (v *boolValue) String() string { return strconv.FormatBool(bool(*v)) }
+
+func (v *boolValue) IsBoolFlag() bool { return true }
+
+type intValue int
+
+func (v *intValue) Set(s string) error {
+	parsed, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", s)
+	}
+	*v = intValue(parsed)
+	return nil
+}
+
+func (v *intValue) String() string { return strconv.Itoa(int(*v)) }
+
+type int64Value int64
+
+func (v *int64Value) Set(s string) error {
+	parsed, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not an integer", s)
+	}
+	*v = int64Value(parsed)
+	return nil
+}
+
+func (v *int64Value) String() string { return strconv.FormatInt(int64(*v), 10) }
+
+type float64Value float64
+
+func (v *float64Value) Set(s string) error {
+	parsed, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("%q is not a number", s)
+	}
+	*v = float64Value(parsed)
+	return nil
+}
+
+func (v *float64Value) String() string { return strconv.FormatFloat(float64(*v), 'g', -1, 64) }
+
+type durationValue time.Duration
+
+func (v *durationValue) Set(s string) error {
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return fmt.Errorf("%q is not a duration like 30s or 5m", s)
+	}
+	*v = durationValue(parsed)
+	return nil
+}
+
+func (v *durationValue) String() string { return time.Duration(*v).String() }
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"pr-reviewer/internal/config"
+)
+
+// ReloadFunc re-reads the configuration and applies the settings that can
+// change at runtime.
+type ReloadFunc func(ctx context.Context) ([]config.Change, error)
+
+// Admin serves operator endpoints guarded by a bearer token.
+type Admin struct {
+	token  string
+	reload ReloadFunc
+}
+
+func NewAdmin(token string, reload ReloadFunc) *Admin {
+	return &Admin{token: token, reload: reload}
+}
+
+func (a *Admin) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && subtle.ConstantTimeCompare([]byte(token), []byte(a.token)) == 1
+}
+
+// Reload handles POST /admin/reload, the HTTP equivalent of SIGHUP.
+func (a *Admin) Reload(w http.ResponseWriter, r *http.Request) {
+	if !a.authorized(r) {
+		sendErrorResponse(w, r, "UNAUTHORIZED", "missing or invalid admin token", http.StatusUnauthorized)
+		return
+	}
+
+	changes, err := a.reload(r.Context())
+	if err != nil {
+		sendErrorResponse(w, r, "INVALID_CONFIG", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"changes": changes,
+	})
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+
+	"pr-reviewer/internal/service"
+)
+
+type bulkPRRequest struct {
+	PullRequestID      string   `json:"pull_request_id"`
+	PullRequestName    string   `json:"pull_request_name"`
+	AuthorID           string   `json:"author_id"`
+	Labels             []string `json:"labels"`
+	AssignmentMode     string   `json:"assignment_mode"`
+	PreferWorkingHours bool     `json:"prefer_working_hours"`
+}
+
+var bulkErrorMessages = map[string]string{
+	"INVALID_PR":   "pull_request_id, pull_request_name and author_id are required and must fit their columns",
+	"PR_EXISTS":    "PR id already exists",
+	"NOT_FOUND":    "resource not found",
+	"INVALID_MODE": "unknown or disabled assignment_mode",
+}
+
+// BulkCreatePRs handles POST /pullRequest/bulkCreate. The body is a JSON
+// array of PRs, or one PR per line with Content-Type application/x-ndjson.
+func (h *Handlers) BulkCreatePRs(w http.ResponseWriter, r *http.Request) {
+	requests, err := decodeBulkPRs(r)
+	if err != nil {
+		sendDecodeError(w, r, err)
+		return
+	}
+
+	prs := make([]service.BulkPR, len(requests))
+	for i, req := range requests {
+		prs[i] = service.BulkPR{
+			PullRequestID:      req.PullRequestID,
+			PullRequestName:    req.PullRequestName,
+			AuthorID:           req.AuthorID,
+			Labels:             req.Labels,
+			Mode:               req.AssignmentMode,
+			PreferWorkingHours: req.PreferWorkingHours,
+		}
+	}
+
+	results, err := h.service.BulkCreatePRs(r.Context(), prs)
+	if err != nil {
+		switch err.Error() {
+		case "INVALID_BULK":
+			sendErrorResponse(w, r, "INVALID_BULK", "no PRs to import", http.StatusBadRequest)
+		case "BULK_TOO_LARGE":
+			sendErrorResponse(w, r, "BULK_TOO_LARGE", fmt.Sprintf("at most %d PRs per request", service.MaxBulkPRs), http.StatusRequestEntityTooLarge)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	created := 0
+	for _, result := range results {
+		if result.Error != nil {
+			result.Error.Message = bulkErrorMessages[result.Error.Code]
+			continue
+		}
+		created++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"created": created,
+		"failed":  len(results) - created,
+		"results": results,
+	})
+}
+
+// decodeBulkPRs reads a JSON array, bounded by MaxBodyBytes, or an NDJSON
+// stream, of which it reads at most MaxBulkPRs+1 items: enough for the
+// service to reject an oversized batch.
+func decodeBulkPRs(r *http.Request) ([]bulkPRRequest, error) {
+	decoder := json.NewDecoder(r.Body)
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "application/x-ndjson" {
+		var requests []bulkPRRequest
+		if err := decoder.Decode(&requests); err != nil {
+			return nil, err
+		}
+		return requests, nil
+	}
+
+	requests := make([]bulkPRRequest, 0)
+	for len(requests) <= service.MaxBulkPRs {
+		var req bulkPRRequest
+		if err := decoder.Decode(&req); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		requests = append(requests, req)
+	}
+	return requests, nil
+}
@@ -1,32 +1,74 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"pr-reviewer/internal/logging"
 	"pr-reviewer/internal/models"
 	"pr-reviewer/internal/service"
+	"strings"
 )
 
 type Handlers struct {
-	service *service.Service
+	service   *service.Service
+	readiness *Readiness
 }
 
-func NewHandlers(service *service.Service) *Handlers {
-	return &Handlers{service: service}
+func NewHandlers(service *service.Service, readiness *Readiness) *Handlers {
+	return &Handlers{service: service, readiness: readiness}
 }
 
-func sendError(w http.ResponseWriter, message string, statusCode int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"error": map[string]interface{}{
-			"code":    "INTERNAL_ERROR",
-			"message": message,
-		},
-	})
+// decodeJSON decodes the request body into v. It answers 413 when the body
+// exceeds the MaxBodyBytes limit and 400 when it is not valid JSON, and
+// reports whether the handler should go on.
+func decodeJSON(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err == nil {
+		return true
+	}
+	sendDecodeError(w, r, err)
+	return false
+}
+
+func sendDecodeError(w http.ResponseWriter, r *http.Request, err error) {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		sendErrorResponse(w, r, "BODY_TOO_LARGE", fmt.Sprintf("request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+		return
+	}
+	sendError(w, r, "Invalid request body", http.StatusBadRequest)
+}
+
+// sendError reports an INTERNAL_ERROR. Server errors are logged with the
+// request id, which is also returned to the client. Errors caused by the
+// request deadline are reported as TIMEOUT, and nothing is sent to clients
+// that have already disconnected.
+func sendError(w http.ResponseWriter, r *http.Request, message string, statusCode int) {
+	if statusCode >= http.StatusInternalServerError {
+		switch {
+		case r.Context().Err() == context.Canceled:
+			logging.FromContext(r.Context()).Info("client disconnected", "path", r.URL.Path, "error", message)
+			return
+		case r.Context().Err() == context.DeadlineExceeded || isTimeout(message):
+			logging.FromContext(r.Context()).Warn("request timed out", "path", r.URL.Path, "error", message)
+			sendErrorResponse(w, r, "TIMEOUT", "request timed out", http.StatusServiceUnavailable)
+			return
+		}
+		logging.FromContext(r.Context()).Error("request failed", "path", r.URL.Path, "status", statusCode, "error", message)
+	}
+	sendErrorResponse(w, r, "INTERNAL_ERROR", message, statusCode)
+}
+
+// isTimeout recognises errors of store calls that hit their own deadline.
+func isTimeout(message string) bool {
+	return strings.Contains(message, context.DeadlineExceeded.Error()) ||
+		strings.Contains(message, "canceling statement due to")
 }
 
-func sendErrorResponse(w http.ResponseWriter, code, message string, statusCode int) {
+func sendErrorResponse(w http.ResponseWriter, r *http.Request, code, message string, statusCode int) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(models.ErrorResponse{
@@ -37,5 +79,6 @@ func sendErrorResponse(w http.ResponseWriter, code, message string, statusCode i
 			Code:    code,
 			Message: message,
 		},
+		RequestID: logging.RequestID(r.Context()),
 	})
 }
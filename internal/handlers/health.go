@@ -1,13 +1,108 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"sync/atomic"
+	"time"
+
+	"pr-reviewer/internal/store"
 )
 
+// Readiness decides whether the instance should receive traffic: the
+// database must answer within timeout with the expected schema, and the
+// instance must not be draining for shutdown.
+type Readiness struct {
+	checker  store.HealthChecker
+	timeout  time.Duration
+	draining atomic.Bool
+}
+
+func NewReadiness(checker store.HealthChecker, timeout time.Duration) *Readiness {
+	return &Readiness{checker: checker, timeout: timeout}
+}
+
+// StartDraining makes /readyz fail so load balancers stop routing new
+// requests here before the server shuts down.
+func (rd *Readiness) StartDraining() {
+	rd.draining.Store(true)
+}
+
+// Livez reports that the process is up; it does not touch the database.
+func (h *Handlers) Livez(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "alive",
+	})
+}
+
+// HealthCheck is the former liveness endpoint, kept for existing probes.
 func (h *Handlers) HealthCheck(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"status": "healthy",
 	})
 }
+
+func (h *Handlers) Readyz(w http.ResponseWriter, r *http.Request) {
+	rd := h.readiness
+	ready := true
+	checks := map[string]string{}
+
+	if rd.draining.Load() {
+		ready = false
+		checks["shutdown"] = "draining"
+	} else {
+		checks["shutdown"] = "ok"
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), rd.timeout)
+	defer cancel()
+
+	version := 0
+	if err := rd.checker.Ping(ctx); err != nil {
+		ready = false
+		checks["database"] = err.Error()
+		checks["migrations"] = "unknown"
+	} else {
+		checks["database"] = "ok"
+		var err error
+		version, err = rd.checker.SchemaVersion(ctx)
+		switch {
+		case err != nil:
+			ready = false
+			checks["migrations"] = err.Error()
+		case version < store.SchemaVersion:
+			ready = false
+			checks["migrations"] = fmt.Sprintf("schema at version %d, expected %d", version, store.SchemaVersion)
+		default:
+			checks["migrations"] = "ok"
+		}
+	}
+
+	stats := rd.checker.PoolStats()
+
+	status, statusCode := "ready", http.StatusOK
+	if !ready {
+		status, statusCode = "not_ready", http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"status":                  status,
+		"checks":                  checks,
+		"schema_version":          version,
+		"expected_schema_version": store.SchemaVersion,
+		"pool": map[string]interface{}{
+			"max_open_connections": stats.MaxOpenConnections,
+			"open_connections":     stats.OpenConnections,
+			"in_use":               stats.InUse,
+			"idle":                 stats.Idle,
+			"wait_count":           stats.WaitCount,
+			"wait_duration_ms":     stats.WaitDuration.Milliseconds(),
+		},
+	})
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/service"
+	"pr-reviewer/internal/store"
+)
+
+type fakeChecker struct {
+	pingErr error
+	version int
+}
+
+func (f fakeChecker) Ping(ctx context.Context) error {
+	return f.pingErr
+}
+
+func (f fakeChecker) SchemaVersion(ctx context.Context) (int, error) {
+	return f.version, nil
+}
+
+func (f fakeChecker) PoolStats() sql.DBStats {
+	return sql.DBStats{MaxOpenConnections: 25}
+}
+
+func TestReadyz(t *testing.T) {
+	tests := []struct {
+		name       string
+		checker    fakeChecker
+		draining   bool
+		wantStatus int
+		wantChecks map[string]string
+	}{
+		{
+			name:       "ready",
+			checker:    fakeChecker{version: store.SchemaVersion},
+			wantStatus: http.StatusOK,
+			wantChecks: map[string]string{"shutdown": "ok", "database": "ok", "migrations": "ok"},
+		},
+		{
+			name:       "draining",
+			checker:    fakeChecker{version: store.SchemaVersion},
+			draining:   true,
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"shutdown": "draining", "database": "ok", "migrations": "ok"},
+		},
+		{
+			name:       "schema behind",
+			checker:    fakeChecker{version: store.SchemaVersion - 1},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"shutdown": "ok", "database": "ok", "migrations": fmt.Sprintf("schema at version %d, expected %d", store.SchemaVersion-1, store.SchemaVersion)},
+		},
+		{
+			name:       "schema not tracked",
+			checker:    fakeChecker{},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"shutdown": "ok", "database": "ok", "migrations": fmt.Sprintf("schema at version 0, expected %d", store.SchemaVersion)},
+		},
+		{
+			name:       "database down",
+			checker:    fakeChecker{pingErr: errors.New("connection refused")},
+			wantStatus: http.StatusServiceUnavailable,
+			wantChecks: map[string]string{"shutdown": "ok", "database": "connection refused", "migrations": "unknown"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readiness := NewReadiness(tt.checker, time.Second)
+			if tt.draining {
+				readiness.StartDraining()
+			}
+			router := NewRouter(service.NewService(nil, 1, nil), RouterConfig{Settings: NewSettingsValue(Settings{}), Readiness: readiness})
+
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			var body struct {
+				Checks        map[string]string `json:"checks"`
+				SchemaVersion int               `json:"schema_version"`
+				Expected      int               `json:"expected_schema_version"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			for check, want := range tt.wantChecks {
+				if got := body.Checks[check]; got != want {
+					t.Errorf("checks[%s] = %q, want %q", check, got, want)
+				}
+			}
+			if body.SchemaVersion != tt.checker.version || body.Expected != store.SchemaVersion {
+				t.Errorf("schema_version %d, expected %d; want %d and %d", body.SchemaVersion, body.Expected, tt.checker.version, store.SchemaVersion)
+			}
+		})
+	}
+}
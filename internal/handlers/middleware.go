@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"time"
+
+	"pr-reviewer/internal/logging"
+	"pr-reviewer/internal/metrics"
+	"pr-reviewer/internal/tracing"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// statusWriter remembers the status code written to the response.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+// withRequestLogging assigns every request an id, taken from X-Request-ID
+// when the client sent a usable one, stores it in the request context and
+// echoes it in the response. The request runs in a server span continuing
+// the client's traceparent, and is logged and counted in the HTTP metrics
+// once it completes.
+func withRequestLogging(router *mux.Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(requestIDHeader)
+		if !validRequestID(requestID) {
+			requestID = newRequestID()
+		}
+		w.Header().Set(requestIDHeader, requestID)
+		r = r.WithContext(logging.WithRequestID(r.Context(), requestID))
+
+		route := "unmatched"
+		var match mux.RouteMatch
+		if router.Match(r, &match) && match.Route != nil {
+			if template, err := match.Route.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		ctx, span := tracing.StartServer(r.Context(), propagation.HeaderCarrier(r.Header), r.Method+" "+route,
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.String("request.id", requestID),
+		)
+		defer span.End()
+		r = r.WithContext(ctx)
+
+		start := time.Now()
+		recorder := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		router.ServeHTTP(recorder, r)
+
+		duration := time.Since(start)
+		metrics.ObserveHTTP(route, r.Method, recorder.status, duration)
+
+		span.SetAttributes(attribute.Int("http.response.status_code", recorder.status))
+		if recorder.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(recorder.status))
+		}
+
+		logging.FromContext(r.Context()).Info("request",
+			"method", r.Method,
+			"route", route,
+			"path", r.URL.Path,
+			"status", recorder.status,
+			"duration_ms", duration.Milliseconds(),
+		)
+	})
+}
+
+// withDeadline bounds the context of each request so that its store calls
+// are cancelled once the current RequestTimeout has passed.
+func withDeadline(settings *SettingsValue) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			timeout := settings.Load().RequestTimeout
+			if timeout <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), timeout)
+			defer cancel()
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// withBodyLimit makes reads past MaxBodyBytes fail, so oversized bodies are
+// rejected by decodeJSON instead of being buffered.
+func withBodyLimit(settings *SettingsValue) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if limit := settings.Load().MaxBodyBytes; limit > 0 {
+				r.Body = http.MaxBytesReader(w, r.Body, limit)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validRequestID accepts ids of up to 128 printable ASCII characters so
+// clients cannot inject arbitrary data into logs.
+func validRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > 128 {
+		return false
+	}
+	for _, c := range requestID {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	raw := make([]byte, 16)
+	rand.Read(raw)
+	return hex.EncodeToString(raw)
+}
@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"pr-reviewer/internal/logging"
+
+	"github.com/gorilla/mux"
+)
+
+// captureLogs makes the default logger write JSON lines to the returned
+// buffer until the test ends.
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+	return &buf
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	generated := regexp.MustCompile(`^[0-9a-f]{32}$`)
+	tests := []struct {
+		name     string
+		incoming string
+		keep     bool
+	}{
+		{name: "incoming id kept", incoming: "client-42", keep: true},
+		{name: "longest id kept", incoming: strings.Repeat("a", 128), keep: true},
+		{name: "missing id generated"},
+		{name: "id with spaces replaced", incoming: "bad id"},
+		{name: "id with control characters replaced", incoming: "id\x1b[31m"},
+		{name: "too long id replaced", incoming: strings.Repeat("a", 129)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			var seen string
+			router := mux.NewRouter()
+			router.HandleFunc("/echo", func(w http.ResponseWriter, r *http.Request) {
+				seen = logging.RequestID(r.Context())
+				logging.FromContext(r.Context()).Info("handled")
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/echo", nil)
+			if tt.incoming != "" {
+				req.Header.Set(requestIDHeader, tt.incoming)
+			}
+			rec := httptest.NewRecorder()
+			withRequestLogging(router).ServeHTTP(rec, req)
+
+			requestID := rec.Header().Get(requestIDHeader)
+			if tt.keep && requestID != tt.incoming {
+				t.Errorf("response id = %q, want the incoming %q", requestID, tt.incoming)
+			}
+			if !tt.keep && !generated.MatchString(requestID) {
+				t.Errorf("response id = %q, want a generated id", requestID)
+			}
+			if seen != requestID {
+				t.Errorf("context id = %q, want %q", seen, requestID)
+			}
+
+			messages := make(map[string]bool)
+			for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+				var entry struct {
+					Msg       string `json:"msg"`
+					RequestID string `json:"request_id"`
+				}
+				if err := json.Unmarshal([]byte(line), &entry); err != nil {
+					t.Fatalf("log line %q: %v", line, err)
+				}
+				if entry.RequestID != requestID {
+					t.Errorf("%q logged request_id %q, want %q", entry.Msg, entry.RequestID, requestID)
+				}
+				messages[entry.Msg] = true
+			}
+			if !messages["handled"] || !messages["request"] {
+				t.Errorf("logged %v, want the handler and request lines", messages)
+			}
+		})
+	}
+}
+
+func TestErrorResponseCarriesRequestID(t *testing.T) {
+	captureLogs(t)
+	req := httptest.NewRequest(http.MethodPost, "/team/delete", strings.NewReader(`{"team_name":"backend"}`))
+	req.Header.Set(requestIDHeader, "client-42")
+	rec := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(rec, req)
+
+	var body struct {
+		RequestID string `json:"request_id"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.RequestID != "client-42" {
+		t.Errorf("request_id = %q, want client-42", body.RequestID)
+	}
+}
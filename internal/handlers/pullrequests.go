@@ -2,28 +2,45 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"net/url"
 	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/service"
+	"strconv"
+	"time"
 )
 
 func (h *Handlers) CreatePR(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		PullRequestID   string `json:"pull_request_id"`
-		PullRequestName string `json:"pull_request_name"`
-		AuthorID        string `json:"author_id"`
+		PullRequestID      string   `json:"pull_request_id"`
+		PullRequestName    string   `json:"pull_request_name"`
+		AuthorID           string   `json:"author_id"`
+		Labels             []string `json:"labels"`
+		AssignmentMode     string   `json:"assignment_mode"`
+		PreferWorkingHours bool     `json:"prefer_working_hours"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	reviewers, err := h.service.AssignReviewers(req.AuthorID)
+	labels := service.NormalizeTags(req.Labels)
+
+	reviewers, err := h.service.AssignReviewers(r.Context(), req.AuthorID, service.AssignOptions{
+		PullRequestID:      req.PullRequestID,
+		Labels:             labels,
+		Mode:               req.AssignmentMode,
+		PreferWorkingHours: req.PreferWorkingHours,
+	})
 	if err != nil {
-		if err.Error() == "NOT_FOUND" {
-			sendErrorResponse(w, "NOT_FOUND", "resource not found", http.StatusNotFound)
-		} else {
-			sendError(w, err.Error(), http.StatusInternalServerError)
+		switch err.Error() {
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		case "INVALID_MODE":
+			sendErrorResponse(w, r, "INVALID_MODE", "unknown or disabled assignment_mode", http.StatusBadRequest)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
@@ -34,16 +51,17 @@ func (h *Handlers) CreatePR(w http.ResponseWriter, r *http.Request) {
 		AuthorID:          req.AuthorID,
 		Status:            "OPEN",
 		AssignedReviewers: reviewers,
+		Labels:            labels,
 	}
 
-	if err := h.service.Store.CreatePR(pr); err != nil {
+	if err := h.service.Store.CreatePR(r.Context(), pr); err != nil {
 		switch err.Error() {
 		case "PR_EXISTS":
-			sendErrorResponse(w, "PR_EXISTS", "PR id already exists", http.StatusConflict)
+			sendErrorResponse(w, r, "PR_EXISTS", "PR id already exists", http.StatusConflict)
 		case "NOT_FOUND":
-			sendErrorResponse(w, "NOT_FOUND", "resource not found", http.StatusNotFound)
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
 		default:
-			sendError(w, err.Error(), http.StatusInternalServerError)
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
@@ -55,32 +73,66 @@ func (h *Handlers) CreatePR(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *Handlers) PreviewAssignment(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PullRequestID      string   `json:"pull_request_id"`
+		AuthorID           string   `json:"author_id"`
+		Labels             []string `json:"labels"`
+		AssignmentMode     string   `json:"assignment_mode"`
+		PreferWorkingHours bool     `json:"prefer_working_hours"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	preview, err := h.service.PreviewAssignment(r.Context(), req.AuthorID, service.AssignOptions{
+		PullRequestID:      req.PullRequestID,
+		Labels:             service.NormalizeTags(req.Labels),
+		Mode:               req.AssignmentMode,
+		PreferWorkingHours: req.PreferWorkingHours,
+	})
+	if err != nil {
+		switch err.Error() {
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		case "INVALID_MODE":
+			sendErrorResponse(w, r, "INVALID_MODE", "unknown or disabled assignment_mode", http.StatusBadRequest)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(preview)
+}
+
 func (h *Handlers) MergePR(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		PullRequestID string `json:"pull_request_id"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	pr, err := h.service.Store.GetPR(req.PullRequestID)
+	pr, err := h.service.Store.GetPR(r.Context(), req.PullRequestID)
 	if err != nil {
 		if err.Error() == "NOT_FOUND" {
-			sendErrorResponse(w, "NOT_FOUND", "resource not found", http.StatusNotFound)
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
 		} else {
-			sendError(w, err.Error(), http.StatusInternalServerError)
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
 
 	if pr.Status != "MERGED" {
-		if err := h.service.Store.MergePR(req.PullRequestID); err != nil {
-			sendError(w, err.Error(), http.StatusInternalServerError)
+		if err := h.service.Store.MergePR(r.Context(), req.PullRequestID); err != nil {
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		pr, _ = h.service.Store.GetPR(req.PullRequestID)
+		pr, _ = h.service.Store.GetPR(r.Context(), req.PullRequestID)
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -89,35 +141,241 @@ func (h *Handlers) MergePR(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (h *Handlers) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
+func (h *Handlers) GetPR(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		sendError(w, r, "pull_request_id is required", http.StatusBadRequest)
+		return
+	}
+
+	pr, err := h.service.Store.GetPR(r.Context(), prID)
+	if err != nil {
+		if err.Error() == "NOT_FOUND" {
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		} else {
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pr": pr,
+	})
+}
+
+func (h *Handlers) ListPRs(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	filter, err := parsePageParams(query)
+	if err != nil {
+		sendErrorResponse(w, r, "INVALID_FILTER", err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	filter.Status = query.Get("status")
+	filter.AuthorID = query.Get("author_id")
+	filter.ReviewerID = query.Get("reviewer_id")
+	filter.TeamName = query.Get("team_name")
+	filter.NameQuery = query.Get("q")
+
+	if filter.Status != "" && filter.Status != "OPEN" && filter.Status != "MERGED" {
+		sendErrorResponse(w, r, "INVALID_FILTER", "status must be OPEN or MERGED", http.StatusBadRequest)
+		return
+	}
+	for param, target := range map[string]**time.Time{"created_from": &filter.CreatedFrom, "created_to": &filter.CreatedTo} {
+		if value := query.Get(param); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				sendErrorResponse(w, r, "INVALID_FILTER", param+" must be an RFC 3339 timestamp", http.StatusBadRequest)
+				return
+			}
+			*target = &parsed
+		}
+	}
+
+	prs, nextCursor, err := h.service.ListPRs(r.Context(), filter, query.Get("cursor"))
+	if err != nil {
+		switch err.Error() {
+		case "INVALID_CURSOR":
+			sendErrorResponse(w, r, "INVALID_CURSOR", "cursor is malformed or was issued for another sort", http.StatusBadRequest)
+		case "INVALID_FILTER":
+			sendErrorResponse(w, r, "INVALID_FILTER", "sort must be created_at or pull_request_name", http.StatusBadRequest)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pull_requests": prs,
+		"next_cursor":   nextCursor,
+	})
+}
+
+// parsePageParams reads the sort, order and limit query parameters shared by
+// paginated PR listings. PRs are listed newest first by default.
+func parsePageParams(query url.Values) (models.PRListFilter, error) {
+	filter := models.PRListFilter{
+		SortBy:     query.Get("sort"),
+		Descending: true,
+	}
+
+	switch query.Get("order") {
+	case "", "desc":
+	case "asc":
+		filter.Descending = false
+	default:
+		return filter, errors.New("order must be asc or desc")
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 {
+			return filter, errors.New("limit must be a positive integer")
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+func (h *Handlers) GetPRHistory(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		sendError(w, r, "pull_request_id is required", http.StatusBadRequest)
+		return
+	}
+
+	history, err := h.service.Store.GetPRHistory(r.Context(), prID)
+	if err != nil {
+		if err.Error() == "NOT_FOUND" {
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		} else {
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pull_request_id": prID,
+		"history":         history,
+	})
+}
+
+func (h *Handlers) AddReviewer(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		PullRequestID string `json:"pull_request_id"`
-		OldUserID     string `json:"old_user_id"`
+		UserID        string `json:"user_id"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
 		return
 	}
 
-	newUserID, err := h.service.ReassignReviewer(req.PullRequestID, req.OldUserID)
+	if err := h.service.AddPRReviewer(r.Context(), req.PullRequestID, req.UserID); err != nil {
+		switch err.Error() {
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		case "PR_MERGED":
+			sendErrorResponse(w, r, "PR_MERGED", "cannot change reviewers on merged PR", http.StatusConflict)
+		case "IS_AUTHOR":
+			sendErrorResponse(w, r, "IS_AUTHOR", "author cannot review own PR", http.StatusConflict)
+		case "USER_INACTIVE":
+			sendErrorResponse(w, r, "USER_INACTIVE", "user is inactive or on leave", http.StatusConflict)
+		case "ALREADY_ASSIGNED":
+			sendErrorResponse(w, r, "ALREADY_ASSIGNED", "reviewer is already assigned to this PR", http.StatusConflict)
+		case "TOO_MANY_REVIEWERS":
+			sendErrorResponse(w, r, "TOO_MANY_REVIEWERS", "team max reviewer count reached", http.StatusConflict)
+		case "NOT_TEAM_MEMBER":
+			sendErrorResponse(w, r, "NOT_TEAM_MEMBER", "reviewer must be a member of the author's team", http.StatusConflict)
+		case "CANDIDATE_NOT_ELIGIBLE":
+			sendErrorResponse(w, r, "CANDIDATE_NOT_ELIGIBLE", "reviewer is not allowed by team rules", http.StatusConflict)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	pr, _ := h.service.Store.GetPR(r.Context(), req.PullRequestID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pr": pr,
+	})
+}
+
+func (h *Handlers) RemoveReviewer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PullRequestID string `json:"pull_request_id"`
+		UserID        string `json:"user_id"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.service.Store.RemovePRReviewer(r.Context(), req.PullRequestID, req.UserID); err != nil {
+		switch err.Error() {
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		case "PR_MERGED":
+			sendErrorResponse(w, r, "PR_MERGED", "cannot change reviewers on merged PR", http.StatusConflict)
+		case "NOT_ASSIGNED":
+			sendErrorResponse(w, r, "NOT_ASSIGNED", "reviewer is not assigned to this PR", http.StatusConflict)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	pr, _ := h.service.Store.GetPR(r.Context(), req.PullRequestID)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pr": pr,
+	})
+}
+
+func (h *Handlers) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PullRequestID  string `json:"pull_request_id"`
+		OldUserID      string `json:"old_user_id"`
+		NewUserID      string `json:"new_user_id"`
+		AssignmentMode string `json:"assignment_mode"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	newUserID, err := h.service.ReassignReviewer(r.Context(), req.PullRequestID, req.OldUserID, service.ReassignOptions{
+		NewUserID: req.NewUserID,
+		Mode:      req.AssignmentMode,
+	})
 	if err != nil {
 		switch err.Error() {
 		case "NOT_FOUND":
-			sendErrorResponse(w, "NOT_FOUND", "resource not found", http.StatusNotFound)
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
 		case "PR_MERGED":
-			sendErrorResponse(w, "PR_MERGED", "cannot reassign on merged PR", http.StatusConflict)
+			sendErrorResponse(w, r, "PR_MERGED", "cannot reassign on merged PR", http.StatusConflict)
 		case "NOT_ASSIGNED":
-			sendErrorResponse(w, "NOT_ASSIGNED", "reviewer is not assigned to this PR", http.StatusConflict)
+			sendErrorResponse(w, r, "NOT_ASSIGNED", "reviewer is not assigned to this PR", http.StatusConflict)
 		case "NO_CANDIDATE":
-			sendErrorResponse(w, "NO_CANDIDATE", "no active replacement candidate in team", http.StatusConflict)
+			sendErrorResponse(w, r, "NO_CANDIDATE", "no active replacement candidate in team", http.StatusConflict)
+		case "CANDIDATE_NOT_ELIGIBLE":
+			sendErrorResponse(w, r, "CANDIDATE_NOT_ELIGIBLE", "new_user_id must be an active, unassigned, non-author member of the reviewer's team allowed by team rules", http.StatusConflict)
+		case "INVALID_MODE":
+			sendErrorResponse(w, r, "INVALID_MODE", "unknown or disabled assignment_mode", http.StatusBadRequest)
 		default:
-			sendError(w, err.Error(), http.StatusInternalServerError)
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
 
-	pr, _ := h.service.Store.GetPR(req.PullRequestID)
+	pr, _ := h.service.Store.GetPR(r.Context(), req.PullRequestID)
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
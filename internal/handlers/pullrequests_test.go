@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/service"
+	"pr-reviewer/internal/store"
+)
+
+// reassignChoiceStore hands ReassignReviewer the candidates the PostgreSQL
+// store would load for pr-1: the active, unassigned members of the team.
+type reassignChoiceStore struct {
+	store.Store
+}
+
+var reassignUsers = map[string]*models.User{
+	"author":   {UserID: "author", TeamName: "backend", IsActive: true},
+	"old":      {UserID: "old", TeamName: "backend", IsActive: true},
+	"kept":     {UserID: "kept", TeamName: "backend", IsActive: true},
+	"inactive": {UserID: "inactive", TeamName: "backend"},
+	"excluded": {UserID: "excluded", TeamName: "backend", IsActive: true},
+	"free":     {UserID: "free", TeamName: "backend", IsActive: true},
+}
+
+func (f reassignChoiceStore) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	if user, ok := reassignUsers[userID]; ok {
+		return user, nil
+	}
+	return nil, errors.New("NOT_FOUND")
+}
+
+func (f reassignChoiceStore) GetPR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	return &models.PullRequest{PullRequestID: prID, AuthorID: "author", Status: "OPEN"}, nil
+}
+
+func (f reassignChoiceStore) ReassignPRReviewer(ctx context.Context, prID, oldUserID string, choose store.ReviewerChooser) (string, error) {
+	return choose(&store.ReviewerChoice{
+		PR:         &models.PullRequest{PullRequestID: prID, AuthorID: "author", AssignedReviewers: []string{"old", "kept"}},
+		OldUser:    reassignUsers["old"],
+		Candidates: []*models.User{reassignUsers["excluded"], reassignUsers["free"]},
+		Rules:      []*models.TeamRule{{ID: 1, Type: service.RuleExclude, AuthorID: "author", ReviewerID: "excluded"}},
+		Kept:       []*models.User{reassignUsers["kept"]},
+	})
+}
+
+func TestReassignReviewerRejectsIneligibleNewUser(t *testing.T) {
+	router := NewRouter(service.NewService(reassignChoiceStore{}, 1, nil), RouterConfig{Settings: NewSettingsValue(Settings{})})
+
+	tests := []struct {
+		newUserID  string
+		wantStatus int
+		wantCode   string
+	}{
+		{"author", http.StatusConflict, "CANDIDATE_NOT_ELIGIBLE"},
+		{"old", http.StatusConflict, "CANDIDATE_NOT_ELIGIBLE"},
+		{"kept", http.StatusConflict, "CANDIDATE_NOT_ELIGIBLE"},
+		{"inactive", http.StatusConflict, "CANDIDATE_NOT_ELIGIBLE"},
+		{"excluded", http.StatusConflict, "CANDIDATE_NOT_ELIGIBLE"},
+		{"missing", http.StatusNotFound, "NOT_FOUND"},
+		{"free", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.newUserID, func(t *testing.T) {
+			body := `{"pull_request_id":"pr-1","old_user_id":"old","new_user_id":"` + tt.newUserID + `"}`
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/pullRequest/reassign", strings.NewReader(body)))
+			if rec.Code != tt.wantStatus {
+				t.Fatalf("status = %d, want %d: %s", rec.Code, tt.wantStatus, rec.Body)
+			}
+
+			if tt.wantCode == "" {
+				var resp struct {
+					ReplacedBy string `json:"replaced_by"`
+				}
+				if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+					t.Fatal(err)
+				}
+				if resp.ReplacedBy != tt.newUserID {
+					t.Errorf("replaced_by = %q, want %q", resp.ReplacedBy, tt.newUserID)
+				}
+				return
+			}
+			var resp models.ErrorResponse
+			if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if resp.Error.Code != tt.wantCode {
+				t.Errorf("code = %q, want %s", resp.Error.Code, tt.wantCode)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Route groups that share a rate limit. Health, metrics and admin routes
+// are not limited.
+const (
+	RouteGroupRead  = "read"
+	RouteGroupWrite = "write"
+)
+
+// How rate-limited clients are told apart. RateLimitByToken uses the
+// Authorization header and falls back to the IP for anonymous requests. The
+// service does not check tokens, so a client can get a fresh bucket by
+// sending a new header; token mode is only safe behind a gateway that
+// rejects unknown tokens.
+const (
+	RateLimitByIP    = "ip"
+	RateLimitByToken = "token"
+)
+
+// RateLimit is a token bucket refilled at Rate requests per second and
+// holding at most Burst requests.
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+const (
+	// Buckets unused for this long are full again and can be dropped.
+	bucketIdleTimeout   = 10 * time.Minute
+	bucketSweepInterval = time.Minute
+)
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// rateLimiter keeps one bucket per route group and client. Limits are read
+// from settings on every request, so reloaded limits apply to existing
+// buckets.
+type rateLimiter struct {
+	settings *SettingsValue
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(settings *SettingsValue) *rateLimiter {
+	return &rateLimiter{settings: settings, buckets: make(map[string]*bucket), lastSweep: time.Now()}
+}
+
+func (rl *rateLimiter) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		settings := rl.settings.Load()
+		group := routeGroup(r)
+		limit, ok := settings.RateLimits[group]
+		if group == "" || !ok || limit.Rate <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := group + "|" + clientKey(r, settings)
+		if wait, allowed := rl.take(key, limit, time.Now()); !allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			sendErrorResponse(w, r, "RATE_LIMITED", "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// take spends a token of key's bucket, or returns how long until one is
+// available.
+func (rl *rateLimiter) take(key string, limit RateLimit, now time.Time) (time.Duration, bool) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	if now.Sub(rl.lastSweep) > bucketSweepInterval {
+		for k, b := range rl.buckets {
+			if now.Sub(b.last) > bucketIdleTimeout {
+				delete(rl.buckets, k)
+			}
+		}
+		rl.lastSweep = now
+	}
+
+	burst := float64(max(limit.Burst, 1))
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &bucket{tokens: burst, last: now}
+		rl.buckets[key] = b
+	}
+
+	b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	b.last = now
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+		return max(wait, time.Second), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+func routeGroup(r *http.Request) string {
+	switch {
+	case r.URL.Path == "/health" || r.URL.Path == "/livez" || r.URL.Path == "/readyz" || r.URL.Path == "/metrics":
+		return ""
+	case strings.HasPrefix(r.URL.Path, "/admin/"):
+		return ""
+	case r.Method == http.MethodGet:
+		return RouteGroupRead
+	default:
+		return RouteGroupWrite
+	}
+}
+
+// clientKey identifies the client of r. Tokens are hashed so the buckets do
+// not keep credentials in memory.
+func clientKey(r *http.Request, settings Settings) string {
+	if settings.RateLimitBy == RateLimitByToken {
+		if token := r.Header.Get("Authorization"); token != "" {
+			sum := sha256.Sum256([]byte(token))
+			return "token:" + hex.EncodeToString(sum[:])
+		}
+	}
+	return "ip:" + clientIP(r, settings.TrustForwardedFor)
+}
+
+// clientIP returns the address the request came from. Behind a proxy it is
+// the rightmost X-Forwarded-For entry, the one appended by the proxy itself;
+// entries to its left are sent by the client and can be forged.
+func clientIP(r *http.Request, trustForwardedFor bool) string {
+	if trustForwardedFor {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			forwarded := values[len(values)-1]
+			if i := strings.LastIndexByte(forwarded, ','); i >= 0 {
+				forwarded = forwarded[i+1:]
+			}
+			if ip := strings.TrimSpace(forwarded); ip != "" {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,166 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/models"
+)
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name      string
+		forwarded []string
+		trust     bool
+		want      string
+	}{
+		{name: "remote address", forwarded: []string{"203.0.113.7"}, trust: false, want: "192.0.2.1"},
+		{name: "no header", trust: true, want: "192.0.2.1"},
+		{name: "single entry", forwarded: []string{"203.0.113.7"}, trust: true, want: "203.0.113.7"},
+		{name: "spoofed entries", forwarded: []string{"10.0.0.1, 10.0.0.2, 203.0.113.7"}, trust: true, want: "203.0.113.7"},
+		{name: "repeated header", forwarded: []string{"10.0.0.1", "198.51.100.4,203.0.113.7"}, trust: true, want: "203.0.113.7"},
+		{name: "empty entry", forwarded: []string{"10.0.0.1, "}, trust: true, want: "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/team/get", nil)
+			req.RemoteAddr = "192.0.2.1:5555"
+			for _, value := range tt.forwarded {
+				req.Header.Add("X-Forwarded-For", value)
+			}
+			if got := clientIP(req, tt.trust); got != tt.want {
+				t.Errorf("clientIP = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimiterRefillsBucket(t *testing.T) {
+	rl := newRateLimiter(NewSettingsValue(Settings{}))
+	limit := RateLimit{Rate: 2, Burst: 2}
+	start := time.Now()
+
+	tests := []struct {
+		after    time.Duration
+		allowed  bool
+		wantWait time.Duration
+	}{
+		{after: 0, allowed: true},
+		{after: 0, allowed: true},
+		{after: 0, allowed: false, wantWait: time.Second},
+		{after: 500 * time.Millisecond, allowed: true},
+		{after: 600 * time.Millisecond, allowed: false, wantWait: time.Second},
+		{after: 10 * time.Second, allowed: true},
+		{after: 10 * time.Second, allowed: true},
+		{after: 10 * time.Second, allowed: false, wantWait: time.Second},
+	}
+	for i, tt := range tests {
+		wait, allowed := rl.take("read|ip:192.0.2.1", limit, start.Add(tt.after))
+		if allowed != tt.allowed || wait != tt.wantWait {
+			t.Errorf("request %d at +%v: allowed %v, wait %v; want %v, %v", i, tt.after, allowed, wait, tt.allowed, tt.wantWait)
+		}
+	}
+
+	slow := RateLimit{Rate: 0.25, Burst: 1}
+	rl.take("write|ip:192.0.2.1", slow, start)
+	if wait, _ := rl.take("write|ip:192.0.2.1", slow, start.Add(time.Second)); wait != 3*time.Second {
+		t.Errorf("wait = %v, want the 3s until the next token", wait)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	settings := NewSettingsValue(Settings{
+		RateLimits: map[string]RateLimit{
+			RouteGroupRead:  {Rate: 0.5, Burst: 1},
+			RouteGroupWrite: {Rate: 0.5, Burst: 1},
+		},
+	})
+	handler := newRateLimiter(settings).middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	serve := func(method, path, remote, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = remote + ":5555"
+		if token != "" {
+			req.Header.Set("Authorization", token)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := serve("GET", "/team/get", "192.0.2.1", ""); rec.Code != http.StatusOK {
+		t.Fatalf("first read: status %d", rec.Code)
+	}
+	rec := serve("GET", "/users/get", "192.0.2.1", "")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "2" {
+		t.Fatalf("second read: status %d, Retry-After %q; want 429 after 2s", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	var body models.ErrorResponse
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil || body.Error.Code != "RATE_LIMITED" {
+		t.Fatalf("body = %+v (%v), want RATE_LIMITED", body, err)
+	}
+
+	tests := []struct {
+		name         string
+		method, path string
+		remote       string
+		token        string
+		want         int
+	}{
+		{"writes have their own bucket", "POST", "/team/add", "192.0.2.1", "", http.StatusOK},
+		{"second write", "POST", "/pullRequest/merge", "192.0.2.1", "", http.StatusTooManyRequests},
+		{"other clients have their own bucket", "GET", "/team/get", "192.0.2.2", "", http.StatusOK},
+		{"probes are not limited", "GET", "/readyz", "192.0.2.1", "", http.StatusOK},
+		{"admin routes are not limited", "POST", "/admin/reload", "192.0.2.1", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		if rec := serve(tt.method, tt.path, tt.remote, tt.token); rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+
+	settings.Store(Settings{
+		RateLimits:  map[string]RateLimit{RouteGroupRead: {Rate: 0.5, Burst: 1}},
+		RateLimitBy: RateLimitByToken,
+	})
+	tokenTests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"first token", "Bearer a", http.StatusOK},
+		{"same token", "Bearer a", http.StatusTooManyRequests},
+		{"other token", "Bearer b", http.StatusOK},
+		{"anonymous falls back to the limited IP", "", http.StatusTooManyRequests},
+	}
+	for _, tt := range tokenTests {
+		if rec := serve("GET", "/team/get", "192.0.2.1", tt.token); rec.Code != tt.want {
+			t.Errorf("%s: status %d, want %d", tt.name, rec.Code, tt.want)
+		}
+	}
+}
+
+func TestClientKeyHashesTokens(t *testing.T) {
+	req := httptest.NewRequest("GET", "/team/get", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	if key := clientKey(req, Settings{RateLimitBy: RateLimitByToken}); strings.Contains(key, "secret") {
+		t.Errorf("key %q contains the token", key)
+	}
+}
+
+func TestBodyLimit(t *testing.T) {
+	router := NewRouter(nil, RouterConfig{Settings: NewSettingsValue(Settings{MaxBodyBytes: 32})})
+
+	req := httptest.NewRequest("POST", "/team/add", strings.NewReader(`{"team_name":"`+strings.Repeat("a", 64)+`"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	var body models.ErrorResponse
+	json.NewDecoder(rec.Body).Decode(&body)
+	if rec.Code != http.StatusRequestEntityTooLarge || body.Error.Code != "BODY_TOO_LARGE" {
+		t.Fatalf("status %d, code %q; want 413 BODY_TOO_LARGE", rec.Code, body.Error.Code)
+	}
+}
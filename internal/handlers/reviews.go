@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+)
+
+func (h *Handlers) RecordReview(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PullRequestID string `json:"pull_request_id"`
+		UserID        string `json:"user_id"`
+		Decision      string `json:"decision"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.service.RecordReviewDecision(r.Context(), req.PullRequestID, req.UserID, req.Decision); err != nil {
+		switch err.Error() {
+		case "INVALID_DECISION":
+			sendErrorResponse(w, r, "INVALID_DECISION", "decision must be approved, changes_requested or commented", http.StatusBadRequest)
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		case "PR_MERGED":
+			sendErrorResponse(w, r, "PR_MERGED", "cannot review merged PR", http.StatusConflict)
+		case "NOT_ASSIGNED":
+			sendErrorResponse(w, r, "NOT_ASSIGNED", "reviewer is not assigned to this PR", http.StatusConflict)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	history, err := h.service.Store.GetPRHistory(r.Context(), req.PullRequestID)
+	if err != nil {
+		sendError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"pull_request_id": req.PullRequestID,
+		"history":         history,
+	})
+}
+
+func (h *Handlers) GetOverdueReviews(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+
+	overdue, err := h.service.OverdueReviews(r.Context(), teamName, time.Now())
+	if err != nil {
+		sendError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"reviews": overdue,
+	})
+}
@@ -1,27 +1,119 @@
 package handlers
 
 import (
+	"net/http"
+	"pr-reviewer/internal/metrics"
 	"pr-reviewer/internal/service"
+	"sync/atomic"
+	"time"
 
 	"github.com/gorilla/mux"
 )
 
-func NewRouter(service *service.Service) *mux.Router {
-	handlers := NewHandlers(service)
+// RouterConfig holds the HTTP settings applied to every route.
+type RouterConfig struct {
+	// Settings are read on every request, so storing new ones takes effect
+	// without rebuilding the router.
+	Settings *SettingsValue
+	// Readiness backs /readyz.
+	Readiness *Readiness
+	// AdminToken and Reload enable POST /admin/reload. The route is not
+	// registered without a token.
+	AdminToken string
+	Reload     ReloadFunc
+}
+
+// Settings are the HTTP settings that can change at runtime.
+type Settings struct {
+	// RequestTimeout is the deadline of each request's context. Zero
+	// disables it.
+	RequestTimeout time.Duration
+	// MaxBodyBytes caps request bodies. Zero disables the limit.
+	MaxBodyBytes int64
+	// RateLimits maps a route group to the limit of each client. Groups
+	// without a limit, or with a zero rate, are not limited.
+	RateLimits map[string]RateLimit
+	// RateLimitBy is RateLimitByIP or RateLimitByToken.
+	RateLimitBy string
+	// TrustForwardedFor takes the client IP from the last X-Forwarded-For
+	// entry, for deployments behind a single proxy that appends it.
+	TrustForwardedFor bool
+}
+
+// SettingsValue holds the current Settings and is safe for concurrent use.
+type SettingsValue struct {
+	current atomic.Pointer[Settings]
+}
+
+func NewSettingsValue(settings Settings) *SettingsValue {
+	v := &SettingsValue{}
+	v.Store(settings)
+	return v
+}
+
+func (v *SettingsValue) Store(settings Settings) {
+	v.current.Store(&settings)
+}
+
+func (v *SettingsValue) Load() Settings {
+	return *v.current.Load()
+}
+
+func NewRouter(service *service.Service, cfg RouterConfig) http.Handler {
+	handlers := NewHandlers(service, cfg.Readiness)
 
 	router := mux.NewRouter()
+	router.Use(newRateLimiter(cfg.Settings).middleware)
+	router.Use(withBodyLimit(cfg.Settings))
+	router.Use(withDeadline(cfg.Settings))
 
 	router.HandleFunc("/team/add", handlers.AddTeam).Methods("POST")
 	router.HandleFunc("/team/get", handlers.GetTeam).Methods("GET")
+	router.HandleFunc("/team/addMembers", handlers.AddTeamMembers).Methods("POST")
+	router.HandleFunc("/team/removeMember", handlers.RemoveTeamMember).Methods("POST")
+	router.HandleFunc("/team/moveMember", handlers.MoveTeamMember).Methods("POST")
+	router.HandleFunc("/team/rename", handlers.RenameTeam).Methods("POST")
+	router.HandleFunc("/team/delete", handlers.DeleteTeam).Methods("POST")
+	router.HandleFunc("/team/sync", handlers.SyncTeams).Methods("PUT")
+	router.HandleFunc("/team/setMaxReviewers", handlers.SetTeamMaxReviewers).Methods("POST")
+	router.HandleFunc("/team/setReviewSLA", handlers.SetTeamReviewSLA).Methods("POST")
+	router.HandleFunc("/team/setEscalation", handlers.SetTeamEscalation).Methods("POST")
+	router.HandleFunc("/team/rules/add", handlers.AddTeamRule).Methods("POST")
+	router.HandleFunc("/team/rules/list", handlers.GetTeamRules).Methods("GET")
+	router.HandleFunc("/team/rules/delete", handlers.DeleteTeamRule).Methods("POST")
+	router.HandleFunc("/team/rules/explain", handlers.ExplainTeamRules).Methods("GET")
 
+	router.HandleFunc("/users/get", handlers.GetUser).Methods("GET")
 	router.HandleFunc("/users/setIsActive", handlers.SetUserActive).Methods("POST")
+	router.HandleFunc("/users/setSkills", handlers.SetUserSkills).Methods("POST")
+	router.HandleFunc("/users/setSeniority", handlers.SetUserSeniority).Methods("POST")
+	router.HandleFunc("/users/setLeave", handlers.SetUserLeave).Methods("POST")
+	router.HandleFunc("/users/setSchedule", handlers.SetUserSchedule).Methods("POST")
 	router.HandleFunc("/users/getReview", handlers.GetUserReviewPRs).Methods("GET")
 
 	router.HandleFunc("/pullRequest/create", handlers.CreatePR).Methods("POST")
+	router.HandleFunc("/pullRequest/bulkCreate", handlers.BulkCreatePRs).Methods("POST")
+	router.HandleFunc("/pullRequest/previewAssignment", handlers.PreviewAssignment).Methods("POST")
 	router.HandleFunc("/pullRequest/merge", handlers.MergePR).Methods("POST")
 	router.HandleFunc("/pullRequest/reassign", handlers.ReassignReviewer).Methods("POST")
+	router.HandleFunc("/pullRequest/addReviewer", handlers.AddReviewer).Methods("POST")
+	router.HandleFunc("/pullRequest/removeReviewer", handlers.RemoveReviewer).Methods("POST")
+	router.HandleFunc("/pullRequest/get", handlers.GetPR).Methods("GET")
+	router.HandleFunc("/pullRequest/list", handlers.ListPRs).Methods("GET")
+	router.HandleFunc("/pullRequest/history", handlers.GetPRHistory).Methods("GET")
+	router.HandleFunc("/pullRequest/review", handlers.RecordReview).Methods("POST")
+
+	router.HandleFunc("/reviews/overdue", handlers.GetOverdueReviews).Methods("GET")
 
 	router.HandleFunc("/health", handlers.HealthCheck).Methods("GET")
+	router.HandleFunc("/livez", handlers.Livez).Methods("GET")
+	router.HandleFunc("/readyz", handlers.Readyz).Methods("GET")
+	router.Handle("/metrics", metrics.Handler()).Methods("GET")
+
+	if cfg.AdminToken != "" && cfg.Reload != nil {
+		admin := NewAdmin(cfg.AdminToken, cfg.Reload)
+		router.HandleFunc("/admin/reload", admin.Reload).Methods("POST")
+	}
 
-	return router
+	return withRequestLogging(router)
 }
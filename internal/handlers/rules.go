@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"pr-reviewer/internal/models"
+)
+
+func (h *Handlers) AddTeamRule(w http.ResponseWriter, r *http.Request) {
+	var rule models.TeamRule
+	if !decodeJSON(w, r, &rule) {
+		return
+	}
+
+	if err := h.service.AddTeamRule(r.Context(), &rule); err != nil {
+		switch err.Error() {
+		case "INVALID_RULE":
+			sendErrorResponse(w, r, "INVALID_RULE", "rule type or its fields are invalid", http.StatusBadRequest)
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"rule": rule,
+	})
+}
+
+func (h *Handlers) GetTeamRules(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		sendError(w, r, "team_name is required", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.service.Store.GetTeam(r.Context(), teamName); err != nil {
+		if err.Error() == "NOT_FOUND" {
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		} else {
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	rules, err := h.service.Store.GetTeamRules(r.Context(), teamName)
+	if err != nil {
+		sendError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rules == nil {
+		rules = []*models.TeamRule{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"team_name": teamName,
+		"rules":     rules,
+	})
+}
+
+func (h *Handlers) DeleteTeamRule(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int64 `json:"id"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.service.Store.DeleteTeamRule(r.Context(), req.ID); err != nil {
+		if err.Error() == "NOT_FOUND" {
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		} else {
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"deleted": req.ID,
+	})
+}
+
+func (h *Handlers) ExplainTeamRules(w http.ResponseWriter, r *http.Request) {
+	authorID := r.URL.Query().Get("author_id")
+	if authorID == "" {
+		sendError(w, r, "author_id is required", http.StatusBadRequest)
+		return
+	}
+
+	explanation, err := h.service.ExplainRules(r.Context(), authorID)
+	if err != nil {
+		if err.Error() == "NOT_FOUND" {
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		} else {
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(explanation)
+}
@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/service"
+	"pr-reviewer/internal/store"
+)
+
+type rulesStore struct {
+	store.Store
+	rules []*models.TeamRule
+}
+
+func (f rulesStore) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	return &models.User{UserID: userID, TeamName: "backend", IsActive: true}, nil
+}
+
+func (f rulesStore) GetActiveTeamMembers(ctx context.Context, teamName, excludeUserID string) ([]*models.User, error) {
+	return []*models.User{
+		{UserID: "u3", TeamName: teamName, IsActive: true, Seniority: service.SenioritySenior},
+		{UserID: "u4", TeamName: teamName, IsActive: true, Seniority: service.SeniorityJunior},
+	}, nil
+}
+
+func (f rulesStore) GetTeamRules(ctx context.Context, teamName string) ([]*models.TeamRule, error) {
+	return f.rules, nil
+}
+
+func TestExplainTeamRulesResponse(t *testing.T) {
+	tests := []struct {
+		name  string
+		rules []*models.TeamRule
+		want  string
+	}{
+		{
+			name: "exclusion and constraint",
+			rules: []*models.TeamRule{
+				{ID: 1, TeamName: "backend", Type: service.RuleExclude, AuthorID: "u7", ReviewerID: "u3"},
+				{ID: 2, TeamName: "backend", Type: service.RulePairJuniorSenior},
+			},
+			want: `{
+				"author_id": "u7",
+				"team_name": "backend",
+				"candidates": [
+					{"user_id": "u3", "seniority": "senior", "eligible": false, "excluded_by": [{"rule_id": 1, "type": "exclude"}]},
+					{"user_id": "u4", "seniority": "junior", "eligible": true}
+				],
+				"constraints": [{"id": 2, "team_name": "backend", "type": "pair_junior_senior"}]
+			}`,
+		},
+		{
+			name: "no rules",
+			want: `{
+				"author_id": "u7",
+				"team_name": "backend",
+				"candidates": [
+					{"user_id": "u3", "seniority": "senior", "eligible": true},
+					{"user_id": "u4", "seniority": "junior", "eligible": true}
+				],
+				"constraints": []
+			}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter(service.NewService(rulesStore{rules: tt.rules}, 1, nil), RouterConfig{Settings: NewSettingsValue(Settings{})})
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, httptest.NewRequest("GET", "/team/rules/explain?author_id=u7", nil))
+			if rec.Code != http.StatusOK {
+				t.Fatalf("status %d: %s", rec.Code, rec.Body)
+			}
+
+			var got, want any
+			if err := json.Unmarshal(rec.Body.Bytes(), &got); err != nil {
+				t.Fatal(err)
+			}
+			if err := json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("response = %s, want %s", rec.Body, tt.want)
+			}
+		})
+	}
+}
@@ -4,21 +4,34 @@ import (
 	"encoding/json"
 	"net/http"
 	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/service"
+	"strconv"
 )
 
 func (h *Handlers) AddTeam(w http.ResponseWriter, r *http.Request) {
 	var team models.Team
-	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
-		sendError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &team) {
 		return
 	}
 
-	if err := h.service.Store.CreateTeam(&team); err != nil {
+	if team.StaleAction != "" && team.StaleAction != models.StaleActionReassign && team.StaleAction != models.StaleActionAddBackup {
+		sendErrorResponse(w, r, "INVALID_ESCALATION", "stale_action must be reassign or add_backup", http.StatusBadRequest)
+		return
+	}
+
+	if err := service.NormalizeTeamMembers(team.Members); err != nil {
+		sendErrorResponse(w, r, "INVALID_SENIORITY", "seniority must be junior, middle, senior or empty", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Store.CreateTeam(r.Context(), &team); err != nil {
 		switch err.Error() {
 		case "TEAM_EXISTS":
-			sendErrorResponse(w, "TEAM_EXISTS", "team_name already exists", http.StatusBadRequest)
+			sendErrorResponse(w, r, "TEAM_EXISTS", "team_name already exists", http.StatusBadRequest)
+		case "USER_IN_OTHER_TEAM":
+			sendErrorResponse(w, r, "USER_IN_OTHER_TEAM", "user belongs to another team, use /team/moveMember", http.StatusConflict)
 		default:
-			sendError(w, err.Error(), http.StatusInternalServerError)
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
@@ -30,19 +43,343 @@ func (h *Handlers) AddTeam(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func (h *Handlers) AddTeamMembers(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string              `json:"team_name"`
+		Members  []models.TeamMember `json:"members"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	team, err := h.service.AddTeamMembers(r.Context(), req.TeamName, req.Members)
+	if err != nil {
+		switch err.Error() {
+		case "INVALID_SENIORITY":
+			sendErrorResponse(w, r, "INVALID_SENIORITY", "seniority must be junior, middle, senior or empty", http.StatusBadRequest)
+		case "USER_IN_OTHER_TEAM":
+			sendErrorResponse(w, r, "USER_IN_OTHER_TEAM", "user belongs to another team, use /team/moveMember", http.StatusConflict)
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"team": team,
+	})
+}
+
+func (h *Handlers) RemoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string `json:"team_name"`
+		UserID   string `json:"user_id"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	handoffs, err := h.service.RemoveTeamMember(r.Context(), req.TeamName, req.UserID)
+	if err != nil {
+		switch err.Error() {
+		case "NOT_TEAM_MEMBER":
+			sendErrorResponse(w, r, "NOT_TEAM_MEMBER", "user is not a member of this team", http.StatusConflict)
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	team, err := h.service.Store.GetTeam(r.Context(), req.TeamName)
+	if err != nil {
+		sendError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"team":     team,
+		"handoffs": handoffs,
+	})
+}
+
+func (h *Handlers) MoveTeamMember(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID       string `json:"user_id"`
+		TeamName     string `json:"team_name"`
+		ReviewPolicy string `json:"review_policy"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	handoffs, err := h.service.MoveTeamMember(r.Context(), req.UserID, req.TeamName, req.ReviewPolicy)
+	if err != nil {
+		switch err.Error() {
+		case "INVALID_POLICY":
+			sendErrorResponse(w, r, "INVALID_POLICY", "review_policy must be reassign or keep", http.StatusBadRequest)
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	user, err := h.service.Store.GetUser(r.Context(), req.UserID)
+	if err != nil {
+		sendError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":     user,
+		"handoffs": handoffs,
+	})
+}
+
+func (h *Handlers) RenameTeam(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName    string `json:"team_name"`
+		NewTeamName string `json:"new_team_name"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.NewTeamName == "" {
+		sendError(w, r, "new_team_name is required", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Store.RenameTeam(r.Context(), req.TeamName, req.NewTeamName); err != nil {
+		switch err.Error() {
+		case "TEAM_EXISTS":
+			sendErrorResponse(w, r, "TEAM_EXISTS", "team_name already exists", http.StatusBadRequest)
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	team, err := h.service.Store.GetTeam(r.Context(), req.NewTeamName)
+	if err != nil {
+		sendError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"team": team,
+	})
+}
+
+func (h *Handlers) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string `json:"team_name"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	handoffs, err := h.service.DeleteTeam(r.Context(), req.TeamName)
+	if err != nil {
+		switch err.Error() {
+		case "TEAM_HAS_OPEN_PRS":
+			sendErrorResponse(w, r, "TEAM_HAS_OPEN_PRS", "team members still author OPEN PRs", http.StatusConflict)
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"team_name": req.TeamName,
+		"handoffs":  handoffs,
+	})
+}
+
+// SyncTeams replaces the whole team directory with the request body. With
+// dry_run=true the diff is computed and nothing is changed.
+func (h *Handlers) SyncTeams(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Teams []models.Team `json:"teams"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	dryRun := false
+	if raw := r.URL.Query().Get("dry_run"); raw != "" {
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			sendError(w, r, "dry_run must be a boolean", http.StatusBadRequest)
+			return
+		}
+		dryRun = parsed
+	}
+
+	diff, err := h.service.SyncTeams(r.Context(), req.Teams, dryRun)
+	if err != nil {
+		switch err.Error() {
+		case "INVALID_SYNC":
+			sendErrorResponse(w, r, "INVALID_SYNC", "teams must be non-empty with unique team names and each user listed once", http.StatusBadRequest)
+		case "INVALID_SENIORITY":
+			sendErrorResponse(w, r, "INVALID_SENIORITY", "seniority must be junior, middle, senior or empty", http.StatusBadRequest)
+		case "INVALID_ESCALATION":
+			sendErrorResponse(w, r, "INVALID_ESCALATION", "stale_action must be reassign or add_backup", http.StatusBadRequest)
+		case "TEAM_HAS_OPEN_PRS":
+			sendErrorResponse(w, r, "TEAM_HAS_OPEN_PRS", "teams to delete still have members authoring OPEN PRs; see blocked_teams with dry_run=true", http.StatusConflict)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"dry_run": dryRun,
+		"diff":    diff,
+	})
+}
+
+func (h *Handlers) SetTeamMaxReviewers(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName     string `json:"team_name"`
+		MaxReviewers int    `json:"max_reviewers"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.MaxReviewers <= 0 {
+		sendError(w, r, "max_reviewers must be positive", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Store.UpdateTeamMaxReviewers(r.Context(), req.TeamName, req.MaxReviewers); err != nil {
+		if err.Error() == "NOT_FOUND" {
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		} else {
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	team, err := h.service.Store.GetTeam(r.Context(), req.TeamName)
+	if err != nil {
+		sendError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"team": team,
+	})
+}
+
+func (h *Handlers) SetTeamReviewSLA(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName       string `json:"team_name"`
+		ReviewSLAHours int    `json:"review_sla_hours"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if req.ReviewSLAHours < 0 {
+		sendError(w, r, "review_sla_hours must not be negative", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.service.Store.UpdateTeamReviewSLA(r.Context(), req.TeamName, req.ReviewSLAHours); err != nil {
+		if err.Error() == "NOT_FOUND" {
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		} else {
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	team, err := h.service.Store.GetTeam(r.Context(), req.TeamName)
+	if err != nil {
+		sendError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"team": team,
+	})
+}
+
+func (h *Handlers) SetTeamEscalation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName        string `json:"team_name"`
+		StaleAfterHours int    `json:"stale_after_hours"`
+		StaleAction     string `json:"stale_action"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	if err := h.service.SetTeamEscalation(r.Context(), req.TeamName, req.StaleAfterHours, req.StaleAction); err != nil {
+		switch err.Error() {
+		case "INVALID_ESCALATION":
+			sendErrorResponse(w, r, "INVALID_ESCALATION", "stale_after_hours must not be negative and stale_action must be reassign or add_backup", http.StatusBadRequest)
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	team, err := h.service.Store.GetTeam(r.Context(), req.TeamName)
+	if err != nil {
+		sendError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"team": team,
+	})
+}
+
 func (h *Handlers) GetTeam(w http.ResponseWriter, r *http.Request) {
 	teamName := r.URL.Query().Get("team_name")
 	if teamName == "" {
-		sendError(w, "team_name is required", http.StatusBadRequest)
+		sendError(w, r, "team_name is required", http.StatusBadRequest)
 		return
 	}
 
-	team, err := h.service.Store.GetTeam(teamName)
+	team, err := h.service.Store.GetTeam(r.Context(), teamName)
 	if err != nil {
 		if err.Error() == "NOT_FOUND" {
-			sendErrorResponse(w, "NOT_FOUND", "resource not found", http.StatusNotFound)
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
 		} else {
-			sendError(w, err.Error(), http.StatusInternalServerError)
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/service"
+	"pr-reviewer/internal/store"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordSpans installs a tracer provider recording every ended span for the
+// duration of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+	return recorder
+}
+
+func spanNamed(t *testing.T, spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	t.Helper()
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	t.Fatalf("no span named %q", name)
+	return nil
+}
+
+// failingStore fails DeleteTeam the way an unreachable database would.
+type failingStore struct {
+	store.Store
+}
+
+func (failingStore) DeleteTeam(ctx context.Context, teamName string, choose store.ReviewerChooser) ([]*models.ReviewHandoff, error) {
+	return nil, errors.New("dial tcp: connection refused")
+}
+
+func newTestRouter() http.Handler {
+	noop := func(context.Context, string, time.Duration, error) {}
+	svc := service.NewService(store.Instrument(failingStore{}, noop), 1, nil)
+	return NewRouter(svc, RouterConfig{Settings: NewSettingsValue(Settings{})})
+}
+
+func TestRequestSpanHierarchy(t *testing.T) {
+	recorder := recordSpans(t)
+
+	req := httptest.NewRequest(http.MethodPost, "/team/delete", strings.NewReader(`{"team_name":"backend"}`))
+	rec := httptest.NewRecorder()
+	newTestRouter().ServeHTTP(rec, req)
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status = %d, want 500", rec.Code)
+	}
+
+	spans := recorder.Ended()
+	server := spanNamed(t, spans, "POST /team/delete")
+	svc := spanNamed(t, spans, "service.DeleteTeam")
+	db := spanNamed(t, spans, "store.DeleteTeam")
+
+	if svc.Parent().SpanID() != server.SpanContext().SpanID() {
+		t.Errorf("service span parent = %s, want the server span %s", svc.Parent().SpanID(), server.SpanContext().SpanID())
+	}
+	if db.Parent().SpanID() != svc.SpanContext().SpanID() {
+		t.Errorf("store span parent = %s, want the service span %s", db.Parent().SpanID(), svc.SpanContext().SpanID())
+	}
+	for _, span := range []sdktrace.ReadOnlySpan{svc, db} {
+		if span.SpanContext().TraceID() != server.SpanContext().TraceID() {
+			t.Errorf("%s is in trace %s, want %s", span.Name(), span.SpanContext().TraceID(), server.SpanContext().TraceID())
+		}
+	}
+	if db.Status().Code != codes.Error {
+		t.Errorf("store span status = %v, want Error", db.Status().Code)
+	}
+	if server.Status().Code != codes.Error {
+		t.Errorf("server span status = %v, want Error", server.Status().Code)
+	}
+}
+
+func TestRequestSpanContinuesTraceparent(t *testing.T) {
+	recorder := recordSpans(t)
+
+	const (
+		traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+		spanID  = "00f067aa0ba902b7"
+	)
+	req := httptest.NewRequest(http.MethodGet, "/livez", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+spanID+"-01")
+	newTestRouter().ServeHTTP(httptest.NewRecorder(), req)
+
+	server := spanNamed(t, recorder.Ended(), "GET /livez")
+	if got := server.SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("trace id = %s, want %s", got, traceID)
+	}
+	if got := server.Parent().SpanID().String(); got != spanID {
+		t.Errorf("parent span id = %s, want %s", got, spanID)
+	}
+	if !server.Parent().IsRemote() {
+		t.Error("parent span is not remote")
+	}
+}
@@ -3,25 +3,189 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/service"
+	"time"
 )
 
+// GetUser returns the user with their current open review load and the first
+// page of PRs they authored; further pages come from /pullRequest/list with
+// author_id and authored_next_cursor.
+func (h *Handlers) GetUser(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		sendError(w, r, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.service.Store.GetUser(r.Context(), userID)
+	if err != nil {
+		if err.Error() == "NOT_FOUND" {
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		} else {
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	counts, err := h.service.Store.GetOpenReviewCounts(r.Context(), []string{userID})
+	if err != nil {
+		sendError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	page, nextCursor, err := h.service.ListPRs(r.Context(), models.PRListFilter{AuthorID: userID, Descending: true}, "")
+	if err != nil {
+		sendError(w, r, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	authored := make([]*models.PullRequestShort, 0, len(page))
+	for _, pr := range page {
+		authored = append(authored, &models.PullRequestShort{
+			PullRequestID:   pr.PullRequestID,
+			PullRequestName: pr.PullRequestName,
+			AuthorID:        pr.AuthorID,
+			Status:          pr.Status,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":                   user,
+		"open_review_count":      counts[userID],
+		"authored_pull_requests": authored,
+		"authored_next_cursor":   nextCursor,
+	})
+}
+
 func (h *Handlers) SetUserActive(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		UserID   string `json:"user_id"`
 		IsActive bool   `json:"is_active"`
 	}
 
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		sendError(w, "Invalid request body", http.StatusBadRequest)
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, handoffs, err := h.service.SetUserActive(r.Context(), req.UserID, req.IsActive)
+	if err != nil {
+		if err.Error() == "NOT_FOUND" {
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		} else {
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user":     user,
+		"handoffs": handoffs,
+	})
+}
+
+func (h *Handlers) SetUserSkills(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID string   `json:"user_id"`
+		Skills []string `json:"skills"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.service.SetUserSkills(r.Context(), req.UserID, req.Skills)
+	if err != nil {
+		if err.Error() == "NOT_FOUND" {
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		} else {
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user": user,
+	})
+}
+
+func (h *Handlers) SetUserSeniority(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID    string `json:"user_id"`
+		Seniority string `json:"seniority"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.service.SetUserSeniority(r.Context(), req.UserID, req.Seniority)
+	if err != nil {
+		switch err.Error() {
+		case "INVALID_SENIORITY":
+			sendErrorResponse(w, r, "INVALID_SENIORITY", "seniority must be junior, middle, senior or empty", http.StatusBadRequest)
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
-	user, err := h.service.Store.UpdateUserActive(req.UserID, req.IsActive)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user": user,
+	})
+}
+
+func (h *Handlers) SetUserLeave(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID     string     `json:"user_id"`
+		LeaveUntil *time.Time `json:"leave_until"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.service.Store.UpdateUserLeave(r.Context(), req.UserID, req.LeaveUntil)
 	if err != nil {
 		if err.Error() == "NOT_FOUND" {
-			sendErrorResponse(w, "NOT_FOUND", "resource not found", http.StatusNotFound)
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
 		} else {
-			sendError(w, err.Error(), http.StatusInternalServerError)
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"user": user,
+	})
+}
+
+func (h *Handlers) SetUserSchedule(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID       string               `json:"user_id"`
+		WorkSchedule *models.WorkSchedule `json:"work_schedule"`
+	}
+
+	if !decodeJSON(w, r, &req) {
+		return
+	}
+
+	user, err := h.service.SetUserSchedule(r.Context(), req.UserID, req.WorkSchedule)
+	if err != nil {
+		switch err.Error() {
+		case "INVALID_SCHEDULE":
+			sendErrorResponse(w, r, "INVALID_SCHEDULE", "timezone must be an IANA name, start/end HH:MM with start before end, days mon..sun", http.StatusBadRequest)
+		case "NOT_FOUND":
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
@@ -35,29 +199,73 @@ func (h *Handlers) SetUserActive(w http.ResponseWriter, r *http.Request) {
 func (h *Handlers) GetUserReviewPRs(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
 	if userID == "" {
-		sendError(w, "user_id is required", http.StatusBadRequest)
+		sendError(w, r, "user_id is required", http.StatusBadRequest)
 		return
 	}
 
-	_, err := h.service.Store.GetUser(userID)
+	_, err := h.service.Store.GetUser(r.Context(), userID)
 	if err != nil {
 		if err.Error() == "NOT_FOUND" {
-			sendErrorResponse(w, "NOT_FOUND", "resource not found", http.StatusNotFound)
+			sendErrorResponse(w, r, "NOT_FOUND", "resource not found", http.StatusNotFound)
 		} else {
-			sendError(w, err.Error(), http.StatusInternalServerError)
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
 		}
 		return
 	}
 
-	prs, err := h.service.Store.GetUserReviewPRs(userID)
+	query := r.URL.Query()
+	filter, err := parsePageParams(query)
 	if err != nil {
-		sendError(w, err.Error(), http.StatusInternalServerError)
+		sendErrorResponse(w, r, "INVALID_FILTER", err.Error(), http.StatusBadRequest)
 		return
 	}
+	filter.ReviewerID = userID
+
+	// Without limit and cursor every PR is returned, as before pagination.
+	unpaged := query.Get("limit") == "" && query.Get("cursor") == ""
+	if unpaged {
+		filter.Limit = service.MaxPageSize
+	}
+
+	var page []*models.PullRequest
+	nextCursor := query.Get("cursor")
+	for {
+		var prs []*models.PullRequest
+		prs, nextCursor, err = h.service.ListPRs(r.Context(), filter, nextCursor)
+		if err != nil {
+			break
+		}
+		page = append(page, prs...)
+		if !unpaged || nextCursor == "" {
+			break
+		}
+	}
+	if err != nil {
+		switch err.Error() {
+		case "INVALID_CURSOR":
+			sendErrorResponse(w, r, "INVALID_CURSOR", "cursor is malformed or was issued for another sort", http.StatusBadRequest)
+		case "INVALID_FILTER":
+			sendErrorResponse(w, r, "INVALID_FILTER", "sort must be created_at or pull_request_name", http.StatusBadRequest)
+		default:
+			sendError(w, r, err.Error(), http.StatusInternalServerError)
+		}
+		return
+	}
+
+	prs := make([]*models.PullRequestShort, 0, len(page))
+	for _, pr := range page {
+		prs = append(prs, &models.PullRequestShort{
+			PullRequestID:   pr.PullRequestID,
+			PullRequestName: pr.PullRequestName,
+			AuthorID:        pr.AuthorID,
+			Status:          pr.Status,
+		})
+	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"user_id":       userID,
 		"pull_requests": prs,
+		"next_cursor":   nextCursor,
 	})
 }
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/service"
+	"pr-reviewer/internal/store"
+)
+
+// reviewStore lists count PRs reviewed by u2, ordered by id.
+type reviewStore struct {
+	store.Store
+	count int
+}
+
+func (f reviewStore) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	if userID != "u2" {
+		return nil, errors.New("NOT_FOUND")
+	}
+	return &models.User{UserID: userID}, nil
+}
+
+func (f reviewStore) ListPRs(ctx context.Context, filter models.PRListFilter) ([]*models.PullRequest, error) {
+	var prs []*models.PullRequest
+	for i := 0; i < f.count && len(prs) < filter.Limit; i++ {
+		id := fmt.Sprintf("pr-%03d", i)
+		if filter.AfterID == "" || id > filter.AfterID {
+			prs = append(prs, &models.PullRequest{PullRequestID: id, AuthorID: "u1", Status: "OPEN"})
+		}
+	}
+	return prs, nil
+}
+
+func TestGetUserReviewPRsPagination(t *testing.T) {
+	router := NewRouter(service.NewService(reviewStore{count: 450}, 1, nil), RouterConfig{Settings: NewSettingsValue(Settings{})})
+	get := func(query string) (int, string) {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest("GET", "/users/getReview?user_id=u2"+query, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", query, rec.Code, rec.Body)
+		}
+		var body struct {
+			PullRequests []*models.PullRequestShort `json:"pull_requests"`
+			NextCursor   string                     `json:"next_cursor"`
+		}
+		if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+			t.Fatal(err)
+		}
+		return len(body.PullRequests), body.NextCursor
+	}
+
+	if count, next := get(""); count != 450 || next != "" {
+		t.Errorf("without limit or cursor: %d PRs, cursor %q; want all 450", count, next)
+	}
+
+	count, next := get("&limit=100")
+	if count != 100 || next == "" {
+		t.Fatalf("limit=100: %d PRs, cursor %q; want a first page", count, next)
+	}
+	if count, _ := get("&cursor=" + next); count != service.DefaultPageSize {
+		t.Errorf("cursor alone: %d PRs, want a default page of %d", count, service.DefaultPageSize)
+	}
+}
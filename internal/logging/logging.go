@@ -0,0 +1,73 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Level is the minimum level of the default logger and can be changed at
+// runtime.
+var Level = new(slog.LevelVar)
+
+// Init installs a JSON logger on stdout as the slog default, and routes the
+// standard log package through it.
+func Init(level string) error {
+	if err := SetLevel(level); err != nil {
+		return err
+	}
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: Level})))
+	return nil
+}
+
+// SetLevel accepts debug, info, warn or error.
+func SetLevel(level string) error {
+	var parsed slog.Level
+	if err := parsed.UnmarshalText([]byte(strings.TrimSpace(level))); err != nil {
+		return fmt.Errorf("invalid log level %q", level)
+	}
+	Level.Set(parsed)
+	return nil
+}
+
+// LogQuery logs a store call at debug level, or at error level when it
+// failed with a database error.
+func LogQuery(ctx context.Context, method string, duration time.Duration, err error, databaseError bool) {
+	logger := FromContext(ctx)
+	if databaseError {
+		logger.Error("store call failed", "method", method, "duration_ms", duration.Milliseconds(), "error", err)
+		return
+	}
+	logger.Debug("store call", "method", method, "duration_ms", duration.Milliseconds())
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context carrying the request id.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// RequestID returns the request id stored in ctx, or "".
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+// FromContext returns the default logger annotated with the request id and
+// trace id carried by ctx, if any.
+func FromContext(ctx context.Context) *slog.Logger {
+	logger := slog.Default()
+	if requestID := RequestID(ctx); requestID != "" {
+		logger = logger.With("request_id", requestID)
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		logger = logger.With("trace_id", spanContext.TraceID().String(), "span_id", spanContext.SpanID().String())
+	}
+	return logger
+}
@@ -0,0 +1,146 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "pr_reviewer"
+
+// Registry holds every metric exposed on /metrics.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by route template, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by route template and method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method"})
+
+	dbDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "db_query_duration_seconds",
+		Help:      "Latency of store calls by method.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method"})
+
+	dbErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "db_query_errors_total",
+		Help:      "Store calls that failed with a database error, by method.",
+	}, []string{"method"})
+
+	assignments = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "assignments_total",
+		Help:      "Reviewer selections by operation and outcome (assigned, no_candidate, error).",
+	}, []string{"operation", "outcome"})
+
+	assignedReviewers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "assigned_reviewers_total",
+		Help:      "Reviewers picked by operation.",
+	}, []string{"operation"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		dbDuration,
+		dbErrors,
+		assignments,
+		assignedReviewers,
+	)
+}
+
+// Handler serves Registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveHTTP records a completed request. route is the matched route
+// template rather than the path, so path values such as ids do not create
+// new series.
+func ObserveHTTP(route, method string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(route, method, strconv.Itoa(status)).Inc()
+	httpDuration.WithLabelValues(route, method).Observe(duration.Seconds())
+}
+
+// ObserveQuery records a store call. Errors carrying one of the store's
+// error codes (NOT_FOUND, PR_MERGED, ...) are expected outcomes and are not
+// counted as database errors.
+func ObserveQuery(ctx context.Context, method string, duration time.Duration, err error) {
+	dbDuration.WithLabelValues(method).Observe(duration.Seconds())
+	if store.IsDatabaseError(err) {
+		dbErrors.WithLabelValues(method).Inc()
+	}
+}
+
+// ObserveAssignment records the outcome of picking reviewers for operation.
+func ObserveAssignment(operation string, assigned int, err error) {
+	switch {
+	case err != nil && err.Error() == "NO_CANDIDATE":
+		assignments.WithLabelValues(operation, "no_candidate").Inc()
+	case err != nil:
+		assignments.WithLabelValues(operation, "error").Inc()
+	case assigned == 0:
+		assignments.WithLabelValues(operation, "no_candidate").Inc()
+	default:
+		assignments.WithLabelValues(operation, "assigned").Inc()
+		assignedReviewers.WithLabelValues(operation).Add(float64(assigned))
+	}
+}
+
+var (
+	openPRsDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "team", "open_pull_requests"),
+		"OPEN PRs authored by members of the team.", []string{"team"}, nil)
+	activeUsersDesc = prometheus.NewDesc(prometheus.BuildFQName(namespace, "team", "active_users"),
+		"Active members of the team.", []string{"team"}, nil)
+)
+
+// teamCollector reads per-team gauges from the store on every scrape.
+type teamCollector struct {
+	stats func(ctx context.Context) ([]*models.TeamStats, error)
+}
+
+// RegisterTeamStats exposes the open PR and active user gauges per team,
+// computed by stats at scrape time.
+func RegisterTeamStats(stats func(ctx context.Context) ([]*models.TeamStats, error)) {
+	Registry.MustRegister(&teamCollector{stats: stats})
+}
+
+func (c *teamCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- openPRsDesc
+	ch <- activeUsersDesc
+}
+
+func (c *teamCollector) Collect(ch chan<- prometheus.Metric) {
+	stats, err := c.stats(context.Background())
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(openPRsDesc, err)
+		return
+	}
+	for _, team := range stats {
+		ch <- prometheus.MustNewConstMetric(openPRsDesc, prometheus.GaugeValue, float64(team.OpenPRs), team.TeamName)
+		ch <- prometheus.MustNewConstMetric(activeUsersDesc, prometheus.GaugeValue, float64(team.ActiveUsers), team.TeamName)
+	}
+}
@@ -4,22 +4,68 @@ import (
 	"time"
 )
 
+// DefaultMaxReviewers is used for teams created without max_reviewers.
+const DefaultMaxReviewers = 2
+
+// Team holds per-team assignment settings. ReviewSLAHours is how long a
+// reviewer may take to make a first decision; StaleAfterHours is how long a
+// review may sit undecided before StaleAction is taken. Zero disables either.
 type Team struct {
-	TeamName string       `json:"team_name"`
-	Members  []TeamMember `json:"members"`
+	TeamName        string       `json:"team_name"`
+	MaxReviewers    int          `json:"max_reviewers,omitempty"`
+	ReviewSLAHours  int          `json:"review_sla_hours,omitempty"`
+	StaleAfterHours int          `json:"stale_after_hours,omitempty"`
+	StaleAction     string       `json:"stale_action,omitempty"`
+	Members         []TeamMember `json:"members"`
+}
+
+// TeamStats is a per-team snapshot reported as metrics.
+type TeamStats struct {
+	TeamName    string
+	OpenPRs     int
+	ActiveUsers int
 }
 
 type TeamMember struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	IsActive bool   `json:"is_active"`
+	UserID    string   `json:"user_id"`
+	Username  string   `json:"username"`
+	IsActive  bool     `json:"is_active"`
+	Skills    []string `json:"skills,omitempty"`
+	Seniority string   `json:"seniority,omitempty"`
 }
 
 type User struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
-	TeamName string `json:"team_name"`
-	IsActive bool   `json:"is_active"`
+	UserID       string        `json:"user_id"`
+	Username     string        `json:"username"`
+	TeamName     string        `json:"team_name"`
+	IsActive     bool          `json:"is_active"`
+	Skills       []string      `json:"skills,omitempty"`
+	Seniority    string        `json:"seniority,omitempty"`
+	LeaveUntil   *time.Time    `json:"leave_until,omitempty"`
+	WorkSchedule *WorkSchedule `json:"work_schedule,omitempty"`
+}
+
+// WorkSchedule is a user's working hours in their own timezone. Start and
+// End are "HH:MM" clock times; Days are lowercase three-letter weekday names.
+type WorkSchedule struct {
+	Timezone string   `json:"timezone"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Days     []string `json:"days,omitempty"`
+}
+
+// BulkCreateResult is the outcome of one PR of a bulk import, reported in
+// request order. Exactly one of PR and Error is set.
+type BulkCreateResult struct {
+	Index         int            `json:"index"`
+	PullRequestID string         `json:"pull_request_id"`
+	PR            *PullRequest   `json:"pr,omitempty"`
+	Error         *BulkItemError `json:"error,omitempty"`
+}
+
+type BulkItemError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 type PullRequest struct {
@@ -28,10 +74,31 @@ type PullRequest struct {
 	AuthorID          string     `json:"author_id"`
 	Status            string     `json:"status"`
 	AssignedReviewers []string   `json:"assigned_reviewers"`
+	Labels            []string   `json:"labels,omitempty"`
 	CreatedAt         *time.Time `json:"createdAt,omitempty"`
 	MergedAt          *time.Time `json:"mergedAt,omitempty"`
 }
 
+// PRListFilter selects and orders PRs for paginated listing. Empty fields do
+// not filter. Results are ordered by SortBy, with NULLs last, and then by
+// pull_request_id. AfterValue/AfterID hold the sort key of the last row of the
+// previous page, and AfterNull is set when its sort value was NULL.
+type PRListFilter struct {
+	Status      string
+	AuthorID    string
+	ReviewerID  string
+	TeamName    string
+	NameQuery   string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	SortBy      string
+	Descending  bool
+	Limit       int
+	AfterValue  string
+	AfterNull   bool
+	AfterID     string
+}
+
 type PullRequestShort struct {
 	PullRequestID   string `json:"pull_request_id"`
 	PullRequestName string `json:"pull_request_name"`
@@ -39,9 +106,112 @@ type PullRequestShort struct {
 	Status          string `json:"status"`
 }
 
+type TeamRule struct {
+	ID         int64      `json:"id"`
+	TeamName   string     `json:"team_name"`
+	Type       string     `json:"type"`
+	AuthorID   string     `json:"author_id,omitempty"`
+	ReviewerID string     `json:"reviewer_id,omitempty"`
+	CreatedAt  *time.Time `json:"createdAt,omitempty"`
+}
+
+// Reasons recorded in the reviewer assignment history.
+const (
+	AssignReasonInitial      = "initial"
+	AssignReasonReassign     = "reassign"
+	AssignReasonManual       = "manual"
+	AssignReasonDeactivation = "deactivation"
+	AssignReasonEscalation   = "escalation"
+	AssignReasonMembership   = "membership"
+)
+
+// Actions taken on reviews that have been stale for longer than the team's
+// StaleAfterHours.
+const (
+	StaleActionReassign  = "reassign"
+	StaleActionAddBackup = "add_backup"
+)
+
+type StaleReview struct {
+	PullRequestID    string
+	AuthorID         string
+	TeamName         string
+	ReviewerID       string
+	AssignedAt       time.Time
+	StaleAfterHours  int
+	Action           string
+	ReviewerSchedule *WorkSchedule
+}
+
+type ReviewerAssignment struct {
+	UserID          string     `json:"user_id"`
+	Reason          string     `json:"reason"`
+	AssignedAt      time.Time  `json:"assignedAt"`
+	UnassignReason  string     `json:"unassign_reason,omitempty"`
+	UnassignedAt    *time.Time `json:"unassignedAt,omitempty"`
+	Decision        string     `json:"decision,omitempty"`
+	FirstDecisionAt *time.Time `json:"firstDecisionAt,omitempty"`
+}
+
+// ReviewHandoff records an OPEN review taken from a reviewer who was
+// deactivated or left their team. NewReviewerID is empty when nobody could
+// take the review over.
+type ReviewHandoff struct {
+	PullRequestID string `json:"pull_request_id"`
+	OldReviewerID string `json:"old_reviewer_id"`
+	NewReviewerID string `json:"new_reviewer_id,omitempty"`
+}
+
+// TeamSyncDiff describes how a team sync changes the database. BlockedTeams
+// are unlisted teams that cannot be deleted while their members author OPEN
+// PRs. Handoffs are the OPEN reviews taken from removed and moved members.
+type TeamSyncDiff struct {
+	CreatedTeams      []string           `json:"created_teams"`
+	DeletedTeams      []string           `json:"deleted_teams"`
+	BlockedTeams      []string           `json:"blocked_teams"`
+	Added             []MembershipChange `json:"added"`
+	Removed           []MembershipChange `json:"removed"`
+	Moved             []MembershipChange `json:"moved"`
+	ActivationChanges []ActivationChange `json:"activation_changes"`
+	Updated           []string           `json:"updated"`
+	Handoffs          []*ReviewHandoff   `json:"handoffs"`
+}
+
+type MembershipChange struct {
+	UserID   string `json:"user_id"`
+	FromTeam string `json:"from_team,omitempty"`
+	ToTeam   string `json:"to_team,omitempty"`
+}
+
+type ActivationChange struct {
+	UserID   string `json:"user_id"`
+	IsActive bool   `json:"is_active"`
+}
+
+// Review decisions a reviewer can record on a PR.
+const (
+	DecisionApproved         = "approved"
+	DecisionChangesRequested = "changes_requested"
+	DecisionCommented        = "commented"
+)
+
+type PendingReview struct {
+	PullRequestID    string        `json:"pull_request_id"`
+	PullRequestName  string        `json:"pull_request_name"`
+	AuthorID         string        `json:"author_id"`
+	TeamName         string        `json:"team_name"`
+	ReviewerID       string        `json:"reviewer_id"`
+	AssignedAt       time.Time     `json:"assignedAt"`
+	SLAHours         int           `json:"sla_hours"`
+	WaitingHours     float64       `json:"waiting_hours"`
+	OverdueHours     float64       `json:"overdue_hours"`
+	ReviewerSchedule *WorkSchedule `json:"-"`
+}
+
 type ErrorResponse struct {
 	Error struct {
 		Code    string `json:"code"`
 		Message string `json:"message"`
 	} `json:"error"`
+	RequestID string `json:"request_id,omitempty"`
 }
@@ -0,0 +1,77 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"pr-reviewer/internal/logging"
+	"pr-reviewer/internal/service"
+)
+
+// escalationLockKey is the Postgres advisory lock key that elects the replica
+// running stale review escalation. Any replica may hold it for one run.
+const escalationLockKey int64 = 0x70727276_65736331
+
+// Scheduler periodically escalates stale reviews. Every replica runs a
+// Scheduler, but only the one that wins the advisory lock acts on a tick.
+type Scheduler struct {
+	service  *service.Service
+	interval time.Duration
+}
+
+func New(service *service.Service, interval time.Duration) *Scheduler {
+	return &Scheduler{service: service, interval: interval}
+}
+
+// Run blocks until ctx is cancelled.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.tick(ctx)
+		}
+	}
+}
+
+func (s *Scheduler) tick(ctx context.Context) {
+	logger := logging.FromContext(ctx).With("job", "escalation")
+	if !s.service.Settings().Escalation {
+		logger.Debug("escalation disabled, skipping run")
+		return
+	}
+
+	unlock, acquired, err := s.service.Store.TryAdvisoryLock(ctx, escalationLockKey)
+	if err != nil {
+		logger.Error("failed to take leader lock", "error", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+	defer func() {
+		if err := unlock(); err != nil {
+			logger.Error("failed to release leader lock", "error", err)
+		}
+	}()
+
+	outcomes, err := s.service.EscalateStaleReviews(ctx, time.Now())
+	if err != nil {
+		logger.Error("failed to load stale reviews", "error", err)
+		return
+	}
+
+	for _, outcome := range outcomes {
+		if outcome.Err != nil {
+			logger.Warn("escalation failed", "action", outcome.Action,
+				"pull_request_id", outcome.PullRequestID, "reviewer_id", outcome.ReviewerID, "error", outcome.Err)
+			continue
+		}
+		logger.Info("review escalated", "action", outcome.Action,
+			"pull_request_id", outcome.PullRequestID, "reviewer_id", outcome.ReviewerID, "new_reviewer_id", outcome.NewReviewerID)
+	}
+}
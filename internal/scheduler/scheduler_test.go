@@ -0,0 +1,65 @@
+package scheduler
+
+import (
+	"context"
+	"testing"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/service"
+	"pr-reviewer/internal/store"
+)
+
+// lockStore grants the advisory lock when acquired is set and counts the
+// escalation runs and lock releases.
+type lockStore struct {
+	store.Store
+	acquired bool
+	runs     int
+	unlocked int
+}
+
+func (f *lockStore) TryAdvisoryLock(ctx context.Context, key int64) (func() error, bool, error) {
+	if key != escalationLockKey {
+		return nil, false, nil
+	}
+	if !f.acquired {
+		return nil, false, nil
+	}
+	return func() error {
+		f.unlocked++
+		return nil
+	}, true, nil
+}
+
+func (f *lockStore) GetStaleReviews(ctx context.Context) ([]*models.StaleReview, error) {
+	f.runs++
+	return nil, nil
+}
+
+func TestTickRunsOnlyOnTheLockHolder(t *testing.T) {
+	tests := []struct {
+		name       string
+		acquired   bool
+		escalation bool
+		wantRuns   int
+	}{
+		{name: "lock holder", acquired: true, escalation: true, wantRuns: 1},
+		{name: "other replica", acquired: false, escalation: true, wantRuns: 0},
+		{name: "escalation disabled", acquired: true, escalation: false, wantRuns: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &lockStore{acquired: tt.acquired}
+			svc := service.NewService(fake, 1, nil)
+			settings := service.DefaultSettings()
+			settings.Escalation = tt.escalation
+			svc.Configure(settings)
+
+			New(svc, 0).tick(context.Background())
+
+			if fake.runs != tt.wantRuns || fake.unlocked != tt.wantRuns {
+				t.Fatalf("runs %d, unlocks %d; want %d of each", fake.runs, fake.unlocked, tt.wantRuns)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/tracing"
+)
+
+const (
+	AssignmentModeRandom = "random"
+	AssignmentModeSkills = "skills"
+)
+
+// Reasons a team member is not a candidate for review.
+const (
+	ReasonAuthor          = "author"
+	ReasonInactive        = "inactive"
+	ReasonOnLeave         = "on_leave"
+	ReasonAlreadyAssigned = "already_assigned"
+	ReasonExcludedByRule  = "excluded_by_rule"
+)
+
+type AssignOptions struct {
+	// PullRequestID keys the random source; the author id is used when empty.
+	PullRequestID string
+	Labels        []string
+	Mode          string
+	// PreferWorkingHours puts reviewers who are currently within their
+	// working hours ahead of those who are not.
+	PreferWorkingHours bool
+
+	// load, when set, replaces the random order with the least loaded
+	// candidates first and carries the load across a bulk import.
+	load reviewLoad
+}
+
+type CandidateEvaluation struct {
+	UserID     string          `json:"user_id"`
+	Eligible   bool            `json:"eligible"`
+	Selected   bool            `json:"selected"`
+	Working    bool            `json:"in_working_hours"`
+	Reasons    []string        `json:"reasons,omitempty"`
+	ExcludedBy []RuleExclusion `json:"excluded_by,omitempty"`
+}
+
+type AssignmentPreview struct {
+	AuthorID   string                `json:"author_id"`
+	TeamName   string                `json:"team_name"`
+	Mode       string                `json:"mode"`
+	Reviewers  []string              `json:"reviewers"`
+	Candidates []CandidateEvaluation `json:"candidates"`
+}
+
+// PreviewAssignment runs the same selection as AssignReviewers without
+// persisting anything. When opts names an existing PR, its current
+// reviewers are reported as already assigned.
+func (s *Service) PreviewAssignment(ctx context.Context, authorID string, opts AssignOptions) (*AssignmentPreview, error) {
+	ctx, span := tracing.Start(ctx, "service.PreviewAssignment")
+	defer span.End()
+
+	var assigned []string
+	if opts.PullRequestID != "" {
+		pr, err := s.Store.GetPR(ctx, opts.PullRequestID)
+		if err != nil && err.Error() != "NOT_FOUND" {
+			return nil, err
+		}
+		if pr != nil {
+			assigned = pr.AssignedReviewers
+		}
+	}
+
+	return s.selectReviewers(ctx, authorID, opts, assigned, 0)
+}
+
+// selectReviewers evaluates every member of the author's team, records why
+// ineligible members were skipped and picks reviewers among the
+// rest according to the assignment mode and the team's rules. limit caps the
+// total number of reviewers including assigned ones; zero means the team's
+// max_reviewers.
+func (s *Service) selectReviewers(ctx context.Context, authorID string, opts AssignOptions, assigned []string, limit int) (*AssignmentPreview, error) {
+	author, err := s.Store.GetUser(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	team, err := s.Store.GetTeam(ctx, author.TeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = team.MaxReviewers
+	}
+
+	members, err := s.Store.GetTeamUsers(ctx, author.TeamName)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := s.loadRules(ctx, author.TeamName, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	mode, err := s.resolveMode(opts.Mode, len(opts.Labels) > 0)
+	if err != nil {
+		return nil, err
+	}
+
+	isAssigned := make(map[string]bool, len(assigned))
+	for _, userID := range assigned {
+		isAssigned[userID] = true
+	}
+
+	now := time.Now()
+	preview := &AssignmentPreview{
+		AuthorID:   authorID,
+		TeamName:   author.TeamName,
+		Mode:       mode,
+		Reviewers:  make([]string, 0, limit),
+		Candidates: make([]CandidateEvaluation, 0, len(members)),
+	}
+
+	eligible := make([]*models.User, 0, len(members))
+	kept := make([]*models.User, 0, len(assigned))
+	for _, member := range members {
+		if isAssigned[member.UserID] {
+			kept = append(kept, member)
+		}
+
+		evaluation := CandidateEvaluation{
+			UserID:  member.UserID,
+			Working: InWorkingHours(member.WorkSchedule, now),
+		}
+		if member.UserID == authorID {
+			evaluation.Reasons = append(evaluation.Reasons, ReasonAuthor)
+		}
+		if !member.IsActive {
+			evaluation.Reasons = append(evaluation.Reasons, ReasonInactive)
+		}
+		if member.LeaveUntil != nil && member.LeaveUntil.After(now) {
+			evaluation.Reasons = append(evaluation.Reasons, ReasonOnLeave)
+		}
+		if isAssigned[member.UserID] {
+			evaluation.Reasons = append(evaluation.Reasons, ReasonAlreadyAssigned)
+		}
+		for _, rule := range rules.excluded[member.UserID] {
+			evaluation.ExcludedBy = append(evaluation.ExcludedBy, RuleExclusion{RuleID: rule.ID, Type: rule.Type})
+		}
+		if len(evaluation.ExcludedBy) > 0 {
+			evaluation.Reasons = append(evaluation.Reasons, ReasonExcludedByRule)
+		}
+
+		evaluation.Eligible = len(evaluation.Reasons) == 0
+		if evaluation.Eligible {
+			eligible = append(eligible, member)
+		}
+		preview.Candidates = append(preview.Candidates, evaluation)
+	}
+
+	key := opts.PullRequestID
+	if key == "" {
+		key = authorID
+	}
+	ordered := shuffleUsers(s.randFor("assign:"+key), eligible)
+	if opts.load != nil {
+		if err := opts.load.fill(ctx, s.Store, eligible); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case mode == AssignmentModeSkills:
+		ordered, err = s.rankBySkillsWithLoad(ctx, ordered, opts.Labels, opts.load)
+		if err != nil {
+			return nil, err
+		}
+	case opts.load != nil:
+		ordered = opts.load.balance(ordered)
+	}
+	if opts.PreferWorkingHours || s.Settings().PreferWorkingHours {
+		ordered = preferWorkingHours(ordered, now)
+	}
+
+	selected := make(map[string]bool, limit)
+	for _, reviewer := range rules.pick(ordered, kept, limit-len(assigned)) {
+		preview.Reviewers = append(preview.Reviewers, reviewer.UserID)
+		selected[reviewer.UserID] = true
+	}
+	for i := range preview.Candidates {
+		preview.Candidates[i].Selected = selected[preview.Candidates[i].UserID]
+	}
+
+	return preview, nil
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/models"
+)
+
+func TestPreviewAssignmentReportsExclusionReasons(t *testing.T) {
+	past, future := time.Now().Add(-time.Hour), time.Now().Add(24*time.Hour)
+	users := activeUsers("author", "inactive", "on-leave", "back", "assigned", "excluded", "free")
+	users[1].IsActive = false
+	users[2].LeaveUntil = &future
+	users[3].LeaveUntil = &past
+	fake := newTeamStore(3, users...)
+	fake.rules = []*models.TeamRule{{ID: 7, Type: RuleExclude, AuthorID: "author", ReviewerID: "excluded"}}
+	fake.prs["pr-1"] = &models.PullRequest{PullRequestID: "pr-1", AuthorID: "author", AssignedReviewers: []string{"assigned"}}
+
+	preview, err := NewService(fake, 1, nil).PreviewAssignment(context.Background(), "author",
+		AssignOptions{PullRequestID: "pr-1", Mode: AssignmentModeRandom})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string][]string{
+		"author":   {ReasonAuthor},
+		"inactive": {ReasonInactive},
+		"on-leave": {ReasonOnLeave},
+		"back":     nil,
+		"assigned": {ReasonAlreadyAssigned},
+		"excluded": {ReasonExcludedByRule},
+		"free":     nil,
+	}
+	if len(preview.Candidates) != len(want) {
+		t.Fatalf("candidates = %+v, want one per team member", preview.Candidates)
+	}
+	for _, candidate := range preview.Candidates {
+		reasons, ok := want[candidate.UserID]
+		if !ok {
+			t.Errorf("unexpected candidate %q", candidate.UserID)
+			continue
+		}
+		if !reflect.DeepEqual(candidate.Reasons, reasons) {
+			t.Errorf("%s: reasons = %v, want %v", candidate.UserID, candidate.Reasons, reasons)
+		}
+		if candidate.Eligible != (reasons == nil) {
+			t.Errorf("%s: eligible = %v, want %v", candidate.UserID, candidate.Eligible, reasons == nil)
+		}
+		if candidate.Selected != candidate.Eligible {
+			t.Errorf("%s: selected = %v, want every eligible member picked for the two free slots", candidate.UserID, candidate.Selected)
+		}
+		if candidate.UserID == "excluded" && !reflect.DeepEqual(candidate.ExcludedBy, []RuleExclusion{{RuleID: 7, Type: RuleExclude}}) {
+			t.Errorf("excluded_by = %+v, want rule 7", candidate.ExcludedBy)
+		}
+	}
+	if len(preview.Reviewers) != 2 {
+		t.Errorf("reviewers = %v, want the two eligible members", preview.Reviewers)
+	}
+}
+
+func TestPreviewAssignmentWithoutPR(t *testing.T) {
+	fake := newTeamStore(2, activeUsers("author", "u1")...)
+	preview, err := NewService(fake, 1, nil).PreviewAssignment(context.Background(), "author",
+		AssignOptions{PullRequestID: "pr-new", Mode: AssignmentModeRandom})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(preview.Reviewers, []string{"u1"}) {
+		t.Errorf("reviewers = %v, want [u1] for a PR that does not exist yet", preview.Reviewers)
+	}
+}
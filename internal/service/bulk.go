@@ -0,0 +1,185 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"pr-reviewer/internal/logging"
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
+	"pr-reviewer/internal/tracing"
+)
+
+// MaxBulkPRs caps one bulk import so its transaction stays short.
+const MaxBulkPRs = 1000
+
+// Column limits of pull_requests, checked up front so one bad item does not
+// fail the batch.
+const (
+	maxPRIDLength   = 100
+	maxPRNameLength = 200
+)
+
+// BulkPR is one PR of a bulk import.
+type BulkPR struct {
+	PullRequestID      string
+	PullRequestName    string
+	AuthorID           string
+	Labels             []string
+	Mode               string
+	PreferWorkingHours bool
+}
+
+// BulkCreatePRs imports OPEN PRs and reports the outcome of each. Reviewers
+// are picked as in AssignReviewers, except that candidates are ordered by
+// open review load and every pick counts towards the load seen by the next
+// PRs, so the batch is spread evenly instead of independently at random.
+// Invalid, duplicate or unassignable items are reported and skipped; the
+// rest are inserted in a single transaction.
+func (s *Service) BulkCreatePRs(ctx context.Context, prs []BulkPR) ([]*models.BulkCreateResult, error) {
+	ctx, span := tracing.Start(ctx, "service.BulkCreatePRs")
+	defer span.End()
+
+	if len(prs) == 0 {
+		return nil, errors.New("INVALID_BULK")
+	}
+	if len(prs) > MaxBulkPRs {
+		return nil, errors.New("BULK_TOO_LARGE")
+	}
+
+	results := make([]*models.BulkCreateResult, len(prs))
+	ids := make([]string, 0, len(prs))
+	seen := make(map[string]bool, len(prs))
+	for i, pr := range prs {
+		results[i] = &models.BulkCreateResult{Index: i, PullRequestID: pr.PullRequestID}
+		switch {
+		case !validBulkPR(pr):
+			results[i].Error = &models.BulkItemError{Code: "INVALID_PR"}
+		case seen[pr.PullRequestID]:
+			results[i].Error = &models.BulkItemError{Code: "PR_EXISTS"}
+		default:
+			seen[pr.PullRequestID] = true
+			ids = append(ids, pr.PullRequestID)
+		}
+	}
+
+	existing, err := s.Store.ExistingPRIDs(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	load := make(reviewLoad)
+	created := make([]*models.PullRequest, 0, len(ids))
+	for i, item := range prs {
+		result := results[i]
+		if result.Error != nil {
+			continue
+		}
+		if existing[item.PullRequestID] {
+			result.Error = &models.BulkItemError{Code: "PR_EXISTS"}
+			continue
+		}
+
+		labels := NormalizeTags(item.Labels)
+		preview, err := s.selectReviewers(ctx, item.AuthorID, AssignOptions{
+			PullRequestID:      item.PullRequestID,
+			Labels:             labels,
+			Mode:               item.Mode,
+			PreferWorkingHours: item.PreferWorkingHours,
+			load:               load,
+		}, nil, 0)
+		if err != nil {
+			if store.IsDatabaseError(err) {
+				return nil, err
+			}
+			s.observer.ObserveAssignment("create", 0, err)
+			result.Error = &models.BulkItemError{Code: err.Error()}
+			continue
+		}
+		load.add(preview.Reviewers)
+
+		result.PR = &models.PullRequest{
+			PullRequestID:     item.PullRequestID,
+			PullRequestName:   item.PullRequestName,
+			AuthorID:          item.AuthorID,
+			Status:            "OPEN",
+			AssignedReviewers: preview.Reviewers,
+			Labels:            labels,
+		}
+		created = append(created, result.PR)
+	}
+
+	skipped, err := s.Store.CreatePRs(ctx, created)
+	if err != nil {
+		return nil, err
+	}
+	raced := make(map[string]bool, len(skipped))
+	for _, id := range skipped {
+		raced[id] = true
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.PR != nil && raced[result.PullRequestID] {
+			result.PR = nil
+			result.Error = &models.BulkItemError{Code: "PR_EXISTS"}
+		}
+		if result.Error != nil {
+			failed++
+			continue
+		}
+		s.observer.ObserveAssignment("create", len(result.PR.AssignedReviewers), nil)
+	}
+
+	logging.FromContext(ctx).Info("pull requests imported", "created", len(results)-failed, "failed", failed, "seed", s.seed)
+	return results, nil
+}
+
+func validBulkPR(pr BulkPR) bool {
+	return pr.PullRequestID != "" && len(pr.PullRequestID) <= maxPRIDLength &&
+		pr.PullRequestName != "" && len(pr.PullRequestName) <= maxPRNameLength &&
+		pr.AuthorID != ""
+}
+
+// reviewLoad counts the OPEN reviews of candidates, including those picked
+// earlier in the same bulk import.
+type reviewLoad map[string]int
+
+// fill loads the stored counts of users not seen yet.
+func (l reviewLoad) fill(ctx context.Context, st store.Store, users []*models.User) error {
+	missing := make([]string, 0, len(users))
+	for _, user := range users {
+		if _, ok := l[user.UserID]; !ok {
+			missing = append(missing, user.UserID)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	counts, err := st.GetOpenReviewCounts(ctx, missing)
+	if err != nil {
+		return err
+	}
+	for _, userID := range missing {
+		l[userID] = counts[userID]
+	}
+	return nil
+}
+
+func (l reviewLoad) add(userIDs []string) {
+	for _, userID := range userIDs {
+		l[userID]++
+	}
+}
+
+// balance orders users by load, keeping the input order for ties.
+func (l reviewLoad) balance(users []*models.User) []*models.User {
+	ordered := make([]*models.User, len(users))
+	copy(ordered, users)
+	sort.SliceStable(ordered, func(i, j int) bool {
+		return l[ordered[i].UserID] < l[ordered[j].UserID]
+	})
+	return ordered
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"pr-reviewer/internal/models"
+)
+
+func (f *teamStore) ExistingPRIDs(ctx context.Context, prIDs []string) (map[string]bool, error) {
+	existing := make(map[string]bool)
+	for _, id := range prIDs {
+		if _, ok := f.prs[id]; ok {
+			existing[id] = true
+		}
+	}
+	return existing, nil
+}
+
+func (f *teamStore) CreatePRs(ctx context.Context, prs []*models.PullRequest) ([]string, error) {
+	for _, pr := range prs {
+		f.prs[pr.PullRequestID] = pr
+	}
+	return nil, nil
+}
+
+// countsStore records which users GetOpenReviewCounts was asked about.
+type countsStore struct {
+	teamStore
+	asked [][]string
+}
+
+func (f *countsStore) GetOpenReviewCounts(ctx context.Context, userIDs []string) (map[string]int, error) {
+	f.asked = append(f.asked, userIDs)
+	return f.openReviews, nil
+}
+
+func TestReviewLoad(t *testing.T) {
+	tests := []struct {
+		name      string
+		load      reviewLoad
+		stored    map[string]int
+		added     []string
+		wantAsked [][]string
+		wantLoad  reviewLoad
+		wantOrder []string
+	}{
+		{
+			name:      "fills unseen users from the store",
+			load:      reviewLoad{},
+			stored:    map[string]int{"u1": 3, "u2": 1},
+			wantAsked: [][]string{{"u1", "u2", "u3"}},
+			wantLoad:  reviewLoad{"u1": 3, "u2": 1, "u3": 0},
+			wantOrder: []string{"u3", "u2", "u1"},
+		},
+		{
+			name:      "keeps counts carried from earlier PRs",
+			load:      reviewLoad{"u1": 0, "u2": 2},
+			stored:    map[string]int{"u1": 3, "u2": 1, "u3": 1},
+			wantAsked: [][]string{{"u3"}},
+			wantLoad:  reviewLoad{"u1": 0, "u2": 2, "u3": 1},
+			wantOrder: []string{"u1", "u3", "u2"},
+		},
+		{
+			name:      "does not query when every user is known",
+			load:      reviewLoad{"u1": 1, "u2": 1, "u3": 1},
+			wantLoad:  reviewLoad{"u1": 1, "u2": 1, "u3": 1},
+			wantOrder: []string{"u1", "u2", "u3"},
+		},
+		{
+			name:      "counts picks towards the next order",
+			load:      reviewLoad{"u1": 0, "u2": 0, "u3": 0},
+			added:     []string{"u1", "u2"},
+			wantLoad:  reviewLoad{"u1": 1, "u2": 1, "u3": 0},
+			wantOrder: []string{"u3", "u1", "u2"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &countsStore{teamStore: teamStore{openReviews: tt.stored}}
+			users := activeUsers("u1", "u2", "u3")
+
+			if err := tt.load.fill(context.Background(), fake, users); err != nil {
+				t.Fatal(err)
+			}
+			tt.load.add(tt.added)
+
+			if !reflect.DeepEqual(fake.asked, tt.wantAsked) {
+				t.Errorf("asked for %v, want %v", fake.asked, tt.wantAsked)
+			}
+			if !reflect.DeepEqual(tt.load, tt.wantLoad) {
+				t.Errorf("load = %v, want %v", tt.load, tt.wantLoad)
+			}
+			var order []string
+			for _, user := range tt.load.balance(users) {
+				order = append(order, user.UserID)
+			}
+			if !reflect.DeepEqual(order, tt.wantOrder) {
+				t.Errorf("order = %v, want %v", order, tt.wantOrder)
+			}
+		})
+	}
+}
+
+func TestBulkCreatePRsSpreadsReviews(t *testing.T) {
+	fake := newTeamStore(2, activeUsers("author", "u1", "u2", "u3", "u4")...)
+	fake.openReviews = map[string]int{"u1": 1}
+	s := NewService(fake, 1, nil)
+
+	prs := make([]BulkPR, 5)
+	for i := range prs {
+		prs[i] = BulkPR{PullRequestID: fmt.Sprintf("pr-%d", i), PullRequestName: "import", AuthorID: "author", Mode: AssignmentModeRandom}
+	}
+	results, err := s.BulkCreatePRs(context.Background(), prs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reviews := map[string]int{"u1": 1}
+	for _, result := range results {
+		if result.Error != nil {
+			t.Fatalf("%s: %s", result.PullRequestID, result.Error.Code)
+		}
+		for _, reviewer := range result.PR.AssignedReviewers {
+			reviews[reviewer]++
+		}
+	}
+	// Ten picks on top of u1's open review leave everyone with two or three.
+	total, lowest, highest := 0, reviews["u1"], reviews["u1"]
+	for _, count := range reviews {
+		total += count
+		lowest, highest = min(lowest, count), max(highest, count)
+	}
+	if len(reviews) != 4 || total != 11 || highest-lowest > 1 {
+		t.Fatalf("reviews = %v, want the load spread within one", reviews)
+	}
+}
+
+func TestBulkCreatePRsRejectsItems(t *testing.T) {
+	valid := func(id string) BulkPR {
+		return BulkPR{PullRequestID: id, PullRequestName: "import", AuthorID: "author"}
+	}
+	tests := []struct {
+		name     string
+		prs      []BulkPR
+		wantErr  string
+		wantCode []string
+	}{
+		{name: "empty", wantErr: "INVALID_BULK"},
+		{name: "too large", prs: make([]BulkPR, MaxBulkPRs+1), wantErr: "BULK_TOO_LARGE"},
+		{
+			name:     "duplicate ids in the batch",
+			prs:      []BulkPR{valid("pr-1"), valid("pr-1"), valid("pr-2")},
+			wantCode: []string{"", "PR_EXISTS", ""},
+		},
+		{
+			name:     "existing ids",
+			prs:      []BulkPR{valid("existing"), valid("pr-1")},
+			wantCode: []string{"PR_EXISTS", ""},
+		},
+		{
+			name:     "invalid items",
+			prs:      []BulkPR{{PullRequestID: "pr-1", AuthorID: "author"}, valid(""), valid("pr-2")},
+			wantCode: []string{"INVALID_PR", "INVALID_PR", ""},
+		},
+		{
+			name:     "unknown author",
+			prs:      []BulkPR{{PullRequestID: "pr-1", PullRequestName: "import", AuthorID: "ghost"}, valid("pr-2")},
+			wantCode: []string{"NOT_FOUND", ""},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := newTeamStore(1, activeUsers("author", "u1", "u2")...)
+			fake.prs["existing"] = &models.PullRequest{PullRequestID: "existing"}
+			s := NewService(fake, 1, nil)
+
+			results, err := s.BulkCreatePRs(context.Background(), tt.prs)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("err = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			codes := make([]string, len(results))
+			for i, result := range results {
+				if result.Error != nil {
+					codes[i] = result.Error.Code
+				} else if _, ok := fake.prs[result.PullRequestID]; !ok {
+					t.Errorf("%s reported created but not stored", result.PullRequestID)
+				}
+			}
+			if !reflect.DeepEqual(codes, tt.wantCode) {
+				t.Errorf("codes = %v, want %v", codes, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestBulkCreatePRsAcceptsMaxBulkPRs(t *testing.T) {
+	fake := newTeamStore(1, activeUsers("author", "u1", "u2")...)
+	s := NewService(fake, 1, nil)
+
+	prs := make([]BulkPR, MaxBulkPRs)
+	for i := range prs {
+		prs[i] = BulkPR{PullRequestID: fmt.Sprintf("pr-%d", i), PullRequestName: "import", AuthorID: "author"}
+	}
+	results, err := s.BulkCreatePRs(context.Background(), prs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.prs) != MaxBulkPRs || len(results) != MaxBulkPRs {
+		t.Fatalf("created %d of %d PRs", len(fake.prs), MaxBulkPRs)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
+	"pr-reviewer/internal/tracing"
+)
+
+type EscalationOutcome struct {
+	PullRequestID string
+	ReviewerID    string
+	Action        string
+	NewReviewerID string
+	Err           error
+}
+
+// EscalateStaleReviews escalates every OPEN review that, as of now, has been
+// waiting longer than its team's stale_after_hours, counted in the
+// reviewer's working hours. Depending on the team's stale_action the
+// reviewer is replaced via ReassignReviewer or joined by a backup reviewer.
+// Reviews without a candidate, or whose PR already has max_reviewers
+// reviewers for a backup, are marked escalated so that later runs skip them.
+func (s *Service) EscalateStaleReviews(ctx context.Context, now time.Time) ([]EscalationOutcome, error) {
+	ctx, span := tracing.Start(ctx, "service.EscalateStaleReviews")
+	defer span.End()
+
+	stale, err := s.Store.GetStaleReviews(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	outcomes := make([]EscalationOutcome, 0)
+	for _, review := range stale {
+		waited := BusinessDuration(review.ReviewerSchedule, review.AssignedAt, now)
+		if waited <= time.Duration(review.StaleAfterHours)*time.Hour {
+			continue
+		}
+
+		outcome := EscalationOutcome{
+			PullRequestID: review.PullRequestID,
+			ReviewerID:    review.ReviewerID,
+			Action:        review.Action,
+		}
+
+		switch review.Action {
+		case models.StaleActionAddBackup:
+			outcome.NewReviewerID, outcome.Err = s.addBackupReviewer(ctx, review)
+		default:
+			outcome.NewReviewerID, outcome.Err = s.ReassignReviewer(ctx, review.PullRequestID, review.ReviewerID, ReassignOptions{})
+		}
+
+		if outcome.Err != nil && (outcome.Err.Error() == "NO_CANDIDATE" || outcome.Err.Error() == "TOO_MANY_REVIEWERS") {
+			if err := s.Store.MarkReviewEscalated(ctx, review.PullRequestID, review.ReviewerID); err != nil {
+				outcome.Err = err
+			}
+		}
+
+		outcomes = append(outcomes, outcome)
+	}
+
+	return outcomes, nil
+}
+
+// addBackupReviewer picks the backup as ReassignReviewer would pick a
+// replacement, from the same candidates and with the stale reviewer kept, in
+// the store transaction that holds the PR lock.
+func (s *Service) addBackupReviewer(ctx context.Context, review *models.StaleReview) (string, error) {
+	mode, err := s.resolveMode("", false)
+	if err != nil {
+		return "", err
+	}
+
+	backupID, err := s.Store.AddBackupReviewer(ctx, review.PullRequestID, review.ReviewerID, func(choice *store.ReviewerChoice) (string, error) {
+		return s.chooseReplacement(choice, ReassignOptions{Mode: mode}, "backup:")
+	})
+	if err == nil {
+		s.observer.ObserveAssignment("backup", 1, nil)
+	} else if err.Error() == "NO_CANDIDATE" {
+		s.observer.ObserveAssignment("backup", 0, err)
+	}
+	return backupID, err
+}
+
+func (s *Service) SetTeamEscalation(ctx context.Context, teamName string, staleAfterHours int, action string) error {
+	ctx, span := tracing.Start(ctx, "service.SetTeamEscalation")
+	defer span.End()
+
+	if staleAfterHours < 0 {
+		return errors.New("INVALID_ESCALATION")
+	}
+	switch action {
+	case "":
+		action = models.StaleActionReassign
+	case models.StaleActionReassign, models.StaleActionAddBackup:
+	default:
+		return errors.New("INVALID_ESCALATION")
+	}
+	return s.Store.UpdateTeamEscalation(ctx, teamName, staleAfterHours, action)
+}
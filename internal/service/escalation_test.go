@@ -0,0 +1,124 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
+)
+
+// escalationStore serves stale reviews and hands the chooser the choice
+// configured for each PR, recording what the service did.
+type escalationStore struct {
+	store.Store
+	stale      []*models.StaleReview
+	choices    map[string]*store.ReviewerChoice
+	backupErrs map[string]error
+	reassigned map[string]string
+	backups    map[string]string
+	marked     []string
+}
+
+func (f *escalationStore) GetStaleReviews(ctx context.Context) ([]*models.StaleReview, error) {
+	return f.stale, nil
+}
+
+func (f *escalationStore) ReassignPRReviewer(ctx context.Context, prID, oldUserID string, choose store.ReviewerChooser) (string, error) {
+	newUserID, err := choose(f.choices[prID])
+	if err != nil {
+		return "", err
+	}
+	f.reassigned[prID] = newUserID
+	return newUserID, nil
+}
+
+func (f *escalationStore) AddBackupReviewer(ctx context.Context, prID, staleUserID string, choose store.ReviewerChooser) (string, error) {
+	if err := f.backupErrs[prID]; err != nil {
+		return "", err
+	}
+	backupID, err := choose(f.choices[prID])
+	if err != nil {
+		return "", err
+	}
+	f.backups[prID] = backupID
+	return backupID, nil
+}
+
+func (f *escalationStore) MarkReviewEscalated(ctx context.Context, prID, userID string) error {
+	f.marked = append(f.marked, prID)
+	return nil
+}
+
+func TestEscalateStaleReviews(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	stale := func(prID, action string, waited time.Duration) *models.StaleReview {
+		return &models.StaleReview{PullRequestID: prID, AuthorID: "author", ReviewerID: "stale",
+			AssignedAt: now.Add(-waited), StaleAfterHours: 24, Action: action}
+	}
+	choice := func(prID string, kept []*models.User, rules []*models.TeamRule, candidates ...*models.User) *store.ReviewerChoice {
+		return &store.ReviewerChoice{
+			PR:         &models.PullRequest{PullRequestID: prID, AuthorID: "author"},
+			OldUser:    &models.User{UserID: "stale", Seniority: SenioritySenior},
+			Candidates: candidates,
+			Rules:      rules,
+			Kept:       kept,
+		}
+	}
+	staleSenior := &models.User{UserID: "stale", Seniority: SenioritySenior}
+	junior := &models.User{UserID: "junior", IsActive: true, Seniority: SeniorityJunior}
+	pairing := []*models.TeamRule{{ID: 1, Type: RulePairJuniorSenior}}
+
+	fake := &escalationStore{
+		stale: []*models.StaleReview{
+			stale("fresh", models.StaleActionReassign, 23*time.Hour),
+			stale("reassign", models.StaleActionReassign, 25*time.Hour),
+			stale("backup", models.StaleActionAddBackup, 25*time.Hour),
+			stale("backup-none", models.StaleActionAddBackup, 25*time.Hour),
+			stale("backup-full", models.StaleActionAddBackup, 25*time.Hour),
+		},
+		choices: map[string]*store.ReviewerChoice{
+			"fresh":    choice("fresh", nil, nil, &models.User{UserID: "u1", IsActive: true}),
+			"reassign": choice("reassign", nil, nil, &models.User{UserID: "u1", IsActive: true}),
+			// The junior may only back up the senior who stays on the PR.
+			"backup":      choice("backup", []*models.User{staleSenior}, pairing, junior),
+			"backup-none": choice("backup-none", []*models.User{staleSenior}, nil),
+		},
+		backupErrs: map[string]error{"backup-full": errors.New("TOO_MANY_REVIEWERS")},
+		reassigned: make(map[string]string),
+		backups:    make(map[string]string),
+	}
+	s := NewService(fake, 1, nil)
+
+	outcomes, err := s.EscalateStaleReviews(context.Background(), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string]EscalationOutcome, len(outcomes))
+	for _, outcome := range outcomes {
+		got[outcome.PullRequestID] = outcome
+	}
+	if _, ok := got["fresh"]; ok || len(outcomes) != 4 {
+		t.Fatalf("outcomes = %+v, want the four reviews past stale_after_hours", outcomes)
+	}
+	if fake.reassigned["reassign"] != "u1" || got["reassign"].NewReviewerID != "u1" {
+		t.Errorf("reassign: outcome %+v, store %v", got["reassign"], fake.reassigned)
+	}
+	if len(fake.reassigned) != 1 {
+		t.Errorf("reassigned %v, want only the reassign PR", fake.reassigned)
+	}
+	if fake.backups["backup"] != "junior" || got["backup"].Action != models.StaleActionAddBackup {
+		t.Errorf("backup: outcome %+v, store %v", got["backup"], fake.backups)
+	}
+	for prID, want := range map[string]string{"backup-none": "NO_CANDIDATE", "backup-full": "TOO_MANY_REVIEWERS"} {
+		if err := got[prID].Err; err == nil || err.Error() != want {
+			t.Errorf("%s: err = %v, want %s", prID, err, want)
+		}
+	}
+	if len(fake.marked) != 2 || fake.marked[0] != "backup-none" || fake.marked[1] != "backup-full" {
+		t.Errorf("marked %v, want the reviews nobody could be added to", fake.marked)
+	}
+}
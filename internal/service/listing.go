@@ -0,0 +1,102 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/tracing"
+)
+
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 200
+)
+
+// prCursor is the opaque pagination token handed to clients. It remembers
+// the sort it was issued for so it cannot be replayed against another one.
+type prCursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d"`
+	Value      string `json:"v"`
+	Null       bool   `json:"n,omitempty"`
+	ID         string `json:"id"`
+}
+
+// ListPRs returns one page of PRs matching filter and the cursor of the next
+// page, which is empty on the last page. filter.AfterValue/AfterID are taken
+// from cursor.
+func (s *Service) ListPRs(ctx context.Context, filter models.PRListFilter, cursor string) ([]*models.PullRequest, string, error) {
+	ctx, span := tracing.Start(ctx, "service.ListPRs")
+	defer span.End()
+
+	if filter.SortBy == "" {
+		filter.SortBy = "created_at"
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultPageSize
+	}
+	if filter.Limit > MaxPageSize {
+		filter.Limit = MaxPageSize
+	}
+
+	if cursor != "" {
+		decoded, err := decodeCursor(cursor)
+		if err != nil || decoded.SortBy != filter.SortBy || decoded.Descending != filter.Descending {
+			return nil, "", errors.New("INVALID_CURSOR")
+		}
+		filter.AfterValue = decoded.Value
+		filter.AfterNull = decoded.Null
+		filter.AfterID = decoded.ID
+	}
+
+	pageSize := filter.Limit
+	filter.Limit = pageSize + 1
+
+	prs, err := s.Store.ListPRs(ctx, filter)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(prs) <= pageSize {
+		return prs, "", nil
+	}
+
+	prs = prs[:pageSize]
+	last := prs[len(prs)-1]
+	next := prCursor{SortBy: filter.SortBy, Descending: filter.Descending, ID: last.PullRequestID}
+	switch filter.SortBy {
+	case "pull_request_name":
+		next.Value = last.PullRequestName
+	default:
+		if last.CreatedAt != nil {
+			next.Value = last.CreatedAt.Format(time.RFC3339Nano)
+		} else {
+			next.Null = true
+		}
+	}
+
+	return prs, encodeCursor(next), nil
+}
+
+func encodeCursor(cursor prCursor) string {
+	raw, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+func decodeCursor(value string) (*prCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	var cursor prCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil {
+		return nil, err
+	}
+	if cursor.ID == "" {
+		return nil, errors.New("INVALID_CURSOR")
+	}
+	return &cursor, nil
+}
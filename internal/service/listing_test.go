@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
+)
+
+// listStore returns the same page for every call and records the filters.
+type listStore struct {
+	store.Store
+	page    []*models.PullRequest
+	filters []models.PRListFilter
+}
+
+func (f *listStore) ListPRs(ctx context.Context, filter models.PRListFilter) ([]*models.PullRequest, error) {
+	f.filters = append(f.filters, filter)
+	return f.page, nil
+}
+
+func TestListPRsCursorKeepsNullSortValue(t *testing.T) {
+	fake := &listStore{page: []*models.PullRequest{
+		{PullRequestID: "pr-1"},
+		{PullRequestID: "pr-2"},
+	}}
+	s := NewService(fake, 1, nil)
+
+	_, cursor, err := s.ListPRs(context.Background(), models.PRListFilter{Limit: 1}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cursor == "" {
+		t.Fatal("no cursor for a full page")
+	}
+	if _, _, err := s.ListPRs(context.Background(), models.PRListFilter{Limit: 1}, cursor); err != nil {
+		t.Fatal(err)
+	}
+
+	after := fake.filters[1]
+	if !after.AfterNull || after.AfterValue != "" || after.AfterID != "pr-1" {
+		t.Fatalf("filter after a NULL created_at = %+v, want AfterNull with AfterID pr-1", after)
+	}
+}
@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/tracing"
+)
+
+const (
+	RuleExclude          = "exclude"
+	RuleRequireSenior    = "require_senior"
+	RulePairJuniorSenior = "pair_junior_senior"
+)
+
+const (
+	SeniorityJunior = "junior"
+	SeniorityMiddle = "middle"
+	SenioritySenior = "senior"
+)
+
+type RuleExclusion struct {
+	RuleID int64  `json:"rule_id"`
+	Type   string `json:"type"`
+}
+
+type CandidateExplanation struct {
+	UserID     string          `json:"user_id"`
+	Seniority  string          `json:"seniority,omitempty"`
+	Eligible   bool            `json:"eligible"`
+	ExcludedBy []RuleExclusion `json:"excluded_by,omitempty"`
+}
+
+type RuleExplanation struct {
+	AuthorID    string                 `json:"author_id"`
+	TeamName    string                 `json:"team_name"`
+	Candidates  []CandidateExplanation `json:"candidates"`
+	Constraints []*models.TeamRule     `json:"constraints"`
+}
+
+func (s *Service) AddTeamRule(ctx context.Context, rule *models.TeamRule) error {
+	ctx, span := tracing.Start(ctx, "service.AddTeamRule")
+	defer span.End()
+
+	switch rule.Type {
+	case RuleExclude:
+		if rule.AuthorID == "" || rule.ReviewerID == "" || rule.AuthorID == rule.ReviewerID {
+			return errors.New("INVALID_RULE")
+		}
+	case RuleRequireSenior, RulePairJuniorSenior:
+		if rule.AuthorID != "" || rule.ReviewerID != "" {
+			return errors.New("INVALID_RULE")
+		}
+	default:
+		return errors.New("INVALID_RULE")
+	}
+
+	if rule.TeamName == "" {
+		return errors.New("INVALID_RULE")
+	}
+
+	return s.Store.CreateTeamRule(ctx, rule)
+}
+
+func ValidSeniority(seniority string) bool {
+	switch seniority {
+	case "", SeniorityJunior, SeniorityMiddle, SenioritySenior:
+		return true
+	}
+	return false
+}
+
+func (s *Service) SetUserSeniority(ctx context.Context, userID, seniority string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "service.SetUserSeniority")
+	defer span.End()
+
+	if !ValidSeniority(seniority) {
+		return nil, errors.New("INVALID_SENIORITY")
+	}
+	return s.Store.UpdateUserSeniority(ctx, userID, seniority)
+}
+
+// ExplainRules reports, for every active teammate of the author, whether the
+// team's exclusion rules allow them to review the author's PRs. Pairing
+// constraints do not exclude anyone on their own and are listed separately.
+func (s *Service) ExplainRules(ctx context.Context, authorID string) (*RuleExplanation, error) {
+	ctx, span := tracing.Start(ctx, "service.ExplainRules")
+	defer span.End()
+
+	author, err := s.Store.GetUser(ctx, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.Store.GetActiveTeamMembers(ctx, author.TeamName, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := s.loadRules(ctx, author.TeamName, authorID)
+	if err != nil {
+		return nil, err
+	}
+
+	explanation := &RuleExplanation{
+		AuthorID:    authorID,
+		TeamName:    author.TeamName,
+		Candidates:  make([]CandidateExplanation, 0, len(members)),
+		Constraints: rules.constraints,
+	}
+	if explanation.Constraints == nil {
+		explanation.Constraints = []*models.TeamRule{}
+	}
+
+	for _, member := range members {
+		candidate := CandidateExplanation{
+			UserID:    member.UserID,
+			Seniority: member.Seniority,
+			Eligible:  true,
+		}
+		for _, rule := range rules.excluded[member.UserID] {
+			candidate.Eligible = false
+			candidate.ExcludedBy = append(candidate.ExcludedBy, RuleExclusion{RuleID: rule.ID, Type: rule.Type})
+		}
+		explanation.Candidates = append(explanation.Candidates, candidate)
+	}
+
+	return explanation, nil
+}
+
+// ruleSet is the subset of a team's rules that applies to one author.
+type ruleSet struct {
+	excluded         map[string][]*models.TeamRule
+	constraints      []*models.TeamRule
+	requireSenior    bool
+	pairJuniorSenior bool
+}
+
+func (s *Service) loadRules(ctx context.Context, teamName, authorID string) (*ruleSet, error) {
+	rules, err := s.Store.GetTeamRules(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+	return newRuleSet(rules, authorID), nil
+}
+
+func newRuleSet(rules []*models.TeamRule, authorID string) *ruleSet {
+	set := &ruleSet{excluded: make(map[string][]*models.TeamRule)}
+	for _, rule := range rules {
+		switch rule.Type {
+		case RuleExclude:
+			if rule.AuthorID == authorID {
+				set.excluded[rule.ReviewerID] = append(set.excluded[rule.ReviewerID], rule)
+			}
+		case RuleRequireSenior:
+			set.requireSenior = true
+			set.constraints = append(set.constraints, rule)
+		case RulePairJuniorSenior:
+			set.pairJuniorSenior = true
+			set.constraints = append(set.constraints, rule)
+		}
+	}
+
+	return set
+}
+
+func (rs *ruleSet) filter(candidates []*models.User) []*models.User {
+	allowed := make([]*models.User, 0, len(candidates))
+	for _, candidate := range candidates {
+		if len(rs.excluded[candidate.UserID]) == 0 {
+			allowed = append(allowed, candidate)
+		}
+	}
+	return allowed
+}
+
+// pick chooses up to count reviewers from ordered, in order, so that together
+// with the already assigned reviewers in kept they satisfy the seniority
+// constraints. Constraints are best effort: when the team has no eligible
+// senior, require_senior is ignored and juniors are left out rather than
+// reviewing without a senior.
+func (rs *ruleSet) pick(ordered, kept []*models.User, count int) []*models.User {
+	if count <= 0 {
+		return nil
+	}
+
+	var seniors []*models.User
+	for _, candidate := range ordered {
+		if candidate.Seniority == SenioritySenior {
+			seniors = append(seniors, candidate)
+		}
+	}
+	noSeniorAvailable := !hasSeniority(kept, SenioritySenior) && len(seniors) == 0
+
+	picked := make([]*models.User, 0, count)
+	for _, candidate := range ordered {
+		if len(picked) == count {
+			break
+		}
+		if rs.pairJuniorSenior && noSeniorAvailable && candidate.Seniority == SeniorityJunior {
+			continue
+		}
+		picked = append(picked, candidate)
+	}
+
+	all := append(append([]*models.User{}, kept...), picked...)
+	if !rs.needsSenior(all) || hasSeniority(all, SenioritySenior) || len(seniors) == 0 {
+		return picked
+	}
+
+	if len(picked) < count {
+		return append(picked, seniors[0])
+	}
+	picked[len(picked)-1] = seniors[0]
+	return picked
+}
+
+func (rs *ruleSet) needsSenior(reviewers []*models.User) bool {
+	return rs.requireSenior || (rs.pairJuniorSenior && hasSeniority(reviewers, SeniorityJunior))
+}
+
+func hasSeniority(users []*models.User, seniority string) bool {
+	for _, user := range users {
+		if user.Seniority == seniority {
+			return true
+		}
+	}
+	return false
+}
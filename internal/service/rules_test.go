@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"pr-reviewer/internal/models"
+)
+
+func usersWithSeniority(pairs ...string) []*models.User {
+	users := make([]*models.User, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		users = append(users, &models.User{UserID: pairs[i], Seniority: pairs[i+1], IsActive: true})
+	}
+	return users
+}
+
+func userIDs(users []*models.User) []string {
+	ids := make([]string, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.UserID)
+	}
+	return ids
+}
+
+func TestRuleSetFilter(t *testing.T) {
+	rules := []*models.TeamRule{
+		{ID: 1, Type: RuleExclude, AuthorID: "author", ReviewerID: "u1"},
+		{ID: 2, Type: RuleExclude, AuthorID: "other", ReviewerID: "u2"},
+		{ID: 3, Type: RuleRequireSenior},
+	}
+	candidates := usersWithSeniority("u1", "", "u2", "", "u3", "")
+
+	if got := userIDs(newRuleSet(rules, "author").filter(candidates)); !reflect.DeepEqual(got, []string{"u2", "u3"}) {
+		t.Errorf("author: allowed %v, want [u2 u3]", got)
+	}
+	if got := userIDs(newRuleSet(rules, "other").filter(candidates)); !reflect.DeepEqual(got, []string{"u1", "u3"}) {
+		t.Errorf("other: allowed %v, want [u1 u3]", got)
+	}
+}
+
+func TestRuleSetPick(t *testing.T) {
+	requireSenior := []*models.TeamRule{{ID: 1, Type: RuleRequireSenior}}
+	pairing := []*models.TeamRule{{ID: 1, Type: RulePairJuniorSenior}}
+
+	tests := []struct {
+		name    string
+		rules   []*models.TeamRule
+		ordered []*models.User
+		kept    []*models.User
+		count   int
+		want    []string
+	}{
+		{"no rules keep the order", nil, usersWithSeniority("j1", SeniorityJunior, "m1", SeniorityMiddle, "s1", SenioritySenior), nil, 2, []string{"j1", "m1"}},
+		{"nothing to pick", requireSenior, usersWithSeniority("s1", SenioritySenior), nil, 0, []string{}},
+		{"fewer candidates than wanted", nil, usersWithSeniority("m1", SeniorityMiddle), nil, 2, []string{"m1"}},
+		{"senior replaces the last pick", requireSenior, usersWithSeniority("m1", SeniorityMiddle, "m2", SeniorityMiddle, "s1", SenioritySenior), nil, 2, []string{"m1", "s1"}},
+		{"kept senior satisfies the rule", requireSenior, usersWithSeniority("m1", SeniorityMiddle, "m2", SeniorityMiddle, "s1", SenioritySenior), usersWithSeniority("s0", SenioritySenior), 2, []string{"m1", "m2"}},
+		{"no senior in the team", requireSenior, usersWithSeniority("m1", SeniorityMiddle, "m2", SeniorityMiddle), nil, 2, []string{"m1", "m2"}},
+		{"junior is paired with a senior", pairing, usersWithSeniority("j1", SeniorityJunior, "m1", SeniorityMiddle, "s1", SenioritySenior), nil, 2, []string{"j1", "s1"}},
+		{"lone junior gives way to a senior", pairing, usersWithSeniority("j1", SeniorityJunior, "s1", SenioritySenior), nil, 1, []string{"s1"}},
+		{"juniors left out without seniors", pairing, usersWithSeniority("j1", SeniorityJunior, "m1", SeniorityMiddle, "m2", SeniorityMiddle), nil, 2, []string{"m1", "m2"}},
+		{"kept senior pairs a junior", pairing, usersWithSeniority("j1", SeniorityJunior), usersWithSeniority("s0", SenioritySenior), 1, []string{"j1"}},
+		{"pairing without juniors", pairing, usersWithSeniority("m1", SeniorityMiddle, "m2", SeniorityMiddle, "s1", SenioritySenior), nil, 2, []string{"m1", "m2"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := userIDs(newRuleSet(tt.rules, "author").pick(tt.ordered, tt.kept, tt.count))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("picked %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func (f *teamStore) GetActiveTeamMembers(ctx context.Context, teamName string, excludeUserID string) ([]*models.User, error) {
+	var members []*models.User
+	for _, user := range f.users {
+		if user.IsActive && user.UserID != excludeUserID {
+			members = append(members, user)
+		}
+	}
+	return members, nil
+}
+
+func TestExplainRules(t *testing.T) {
+	fake := newTeamStore(2, usersWithSeniority("author", "", "u1", SenioritySenior, "u2", SeniorityJunior)...)
+	fake.rules = []*models.TeamRule{
+		{ID: 1, Type: RuleExclude, AuthorID: "author", ReviewerID: "u1"},
+		{ID: 2, Type: RuleExclude, AuthorID: "u2", ReviewerID: "author"},
+		{ID: 3, Type: RulePairJuniorSenior},
+	}
+
+	explanation, err := NewService(fake, 1, nil).ExplainRules(context.Background(), "author")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &RuleExplanation{
+		AuthorID: "author",
+		TeamName: "backend",
+		Candidates: []CandidateExplanation{
+			{UserID: "u1", Seniority: SenioritySenior, ExcludedBy: []RuleExclusion{{RuleID: 1, Type: RuleExclude}}},
+			{UserID: "u2", Seniority: SeniorityJunior, Eligible: true},
+		},
+		Constraints: []*models.TeamRule{fake.rules[2]},
+	}
+	if !reflect.DeepEqual(explanation, want) {
+		t.Errorf("explanation = %+v, want %+v", explanation, want)
+	}
+}
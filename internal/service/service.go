@@ -1,113 +1,264 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"hash/fnv"
 	"math/rand"
+	"pr-reviewer/internal/logging"
 	"pr-reviewer/internal/models"
 	"pr-reviewer/internal/store"
-	"time"
+	"pr-reviewer/internal/tracing"
+	"sync/atomic"
 )
 
 type Service struct {
-	Store store.Store
+	Store    store.Store
+	seed     int64
+	observer AssignmentObserver
+	settings atomic.Pointer[Settings]
 }
 
-func NewService(store store.Store) *Service {
-	rand.Seed(time.Now().UnixNano())
-	return &Service{Store: store}
+// AssignmentObserver is told the outcome of every reviewer selection, so
+// assignments can be reported without the service depending on a metrics
+// library. operation is create, reassign or backup.
+type AssignmentObserver interface {
+	ObserveAssignment(operation string, assigned int, err error)
 }
 
-func (s *Service) AssignReviewers(authorID string) ([]string, error) {
-	author, err := s.Store.GetUser(authorID)
-	if err != nil {
-		return nil, err
-	}
+// AssignmentObserverFunc adapts a function to AssignmentObserver.
+type AssignmentObserverFunc func(operation string, assigned int, err error)
 
-	teamMembers, err := s.Store.GetActiveTeamMembers(author.TeamName, authorID)
-	if err != nil {
-		return nil, err
-	}
+func (f AssignmentObserverFunc) ObserveAssignment(operation string, assigned int, err error) {
+	f(operation, assigned, err)
+}
 
-	reviewers := make([]string, 0, 2)
-	shuffled := shuffleUsers(teamMembers)
+// Settings are the assignment defaults and feature toggles that can be
+// swapped at runtime with Configure.
+type Settings struct {
+	// DefaultMode is used by requests without an assignment mode. When empty,
+	// assignment uses the skills mode for labelled PRs and random otherwise.
+	DefaultMode string
+	// PreferWorkingHours applies to every assignment, not only to requests
+	// asking for it.
+	PreferWorkingHours bool
+	// SkillsAssignment enables AssignmentModeSkills.
+	SkillsAssignment bool
+	// Escalation enables the background escalation of stale reviews.
+	Escalation bool
+}
 
-	for i := 0; i < len(shuffled) && i < 2; i++ {
-		reviewers = append(reviewers, shuffled[i].UserID)
+func DefaultSettings() Settings {
+	return Settings{SkillsAssignment: true, Escalation: true}
+}
+
+// NewService creates a service whose random choices are derived from seed.
+// Each request gets its own source built from the seed and the request's key
+// (usually the PR id), so the same seed and input always pick the same
+// reviewers and concurrent requests never share a source. A nil observer
+// discards the assignment outcomes.
+func NewService(store store.Store, seed int64, observer AssignmentObserver) *Service {
+	if observer == nil {
+		observer = AssignmentObserverFunc(func(string, int, error) {})
 	}
+	s := &Service{Store: store, seed: seed, observer: observer}
+	s.Configure(DefaultSettings())
+	return s
+}
 
-	return reviewers, nil
+// Configure replaces the settings used by subsequent calls.
+func (s *Service) Configure(settings Settings) {
+	s.settings.Store(&settings)
 }
 
-func (s *Service) ReassignReviewer(prID, oldUserID string) (string, error) {
-	pr, err := s.Store.GetPR(prID)
-	if err != nil {
-		return "", err
-	}
+func (s *Service) Settings() Settings {
+	return *s.settings.Load()
+}
 
-	if pr.Status == "MERGED" {
-		return "", errors.New("PR_MERGED")
+// resolveMode applies the default mode to an empty mode and rejects unknown
+// or disabled ones. labelled tells whether the PR has labels to match.
+func (s *Service) resolveMode(mode string, labelled bool) (string, error) {
+	settings := s.Settings()
+	if mode == "" {
+		mode = settings.DefaultMode
 	}
-
-	isAssigned, err := s.Store.IsUserAssignedToPR(prID, oldUserID)
-	if err != nil {
-		return "", err
+	if mode == "" {
+		mode = AssignmentModeRandom
+		if labelled && settings.SkillsAssignment {
+			mode = AssignmentModeSkills
+		}
 	}
-	if !isAssigned {
-		return "", errors.New("NOT_ASSIGNED")
+
+	switch {
+	case mode == AssignmentModeRandom:
+	case mode == AssignmentModeSkills && settings.SkillsAssignment:
+	default:
+		return "", errors.New("INVALID_MODE")
 	}
+	return mode, nil
+}
+
+func (s *Service) Seed() int64 {
+	return s.seed
+}
+
+func (s *Service) randFor(key string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return rand.New(rand.NewSource(s.seed ^ int64(h.Sum64())))
+}
 
-	oldUser, err := s.Store.GetUser(oldUserID)
+func (s *Service) AssignReviewers(ctx context.Context, authorID string, opts AssignOptions) ([]string, error) {
+	ctx, span := tracing.Start(ctx, "service.AssignReviewers")
+	defer span.End()
+
+	preview, err := s.selectReviewers(ctx, authorID, opts, nil, 0)
 	if err != nil {
-		return "", err
+		s.observer.ObserveAssignment("create", 0, err)
+		return nil, err
 	}
+	s.observer.ObserveAssignment("create", len(preview.Reviewers), nil)
+	logging.FromContext(ctx).Info("reviewers selected", "pull_request_id", opts.PullRequestID,
+		"author_id", authorID, "mode", preview.Mode, "reviewers", preview.Reviewers, "seed", s.seed)
+	return preview.Reviewers, nil
+}
+
+func (s *Service) SetUserSkills(ctx context.Context, userID string, skills []string) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "service.SetUserSkills")
+	defer span.End()
+
+	return s.Store.UpdateUserSkills(ctx, userID, NormalizeTags(skills))
+}
+
+// SetUserActive sets the user's active flag. A deactivated user hands each
+// of their OPEN reviews to another member of the PR author's team.
+func (s *Service) SetUserActive(ctx context.Context, userID string, isActive bool) (*models.User, []*models.ReviewHandoff, error) {
+	ctx, span := tracing.Start(ctx, "service.SetUserActive")
+	defer span.End()
 
-	candidates, err := s.Store.GetActiveTeamMembers(oldUser.TeamName, "")
+	return s.Store.UpdateUserActive(ctx, userID, isActive, s.handoffChooser(ctx))
+}
+
+type ReassignOptions struct {
+	// NewUserID, when set, is the replacement the caller wants. It must pass
+	// the same checks as a randomly chosen candidate.
+	NewUserID string
+	// Mode picks the replacement strategy when NewUserID is empty. The
+	// skills mode ranks candidates against the PR labels.
+	Mode string
+}
+
+// ReassignReviewer replaces oldUserID on the PR. The checks and the swap run
+// in a single store transaction holding the PR lock, so racing reassigns or
+// merges cannot leave duplicated reviewers or change a MERGED PR.
+func (s *Service) ReassignReviewer(ctx context.Context, prID, oldUserID string, opts ReassignOptions) (string, error) {
+	ctx, span := tracing.Start(ctx, "service.ReassignReviewer")
+	defer span.End()
+
+	mode, err := s.resolveMode(opts.Mode, false)
 	if err != nil {
 		return "", err
 	}
 
-	availableCandidates := make([]*models.User, 0)
-	for _, candidate := range candidates {
-		if candidate.UserID == pr.AuthorID {
-			continue
-		}
-
-		isAssigned, err := s.Store.IsUserAssignedToPR(prID, candidate.UserID)
-		if err != nil {
+	if opts.NewUserID != "" {
+		if _, err := s.Store.GetUser(ctx, opts.NewUserID); err != nil {
 			return "", err
 		}
-		if !isAssigned {
-			availableCandidates = append(availableCandidates, candidate)
+	}
+
+	newUserID, err := s.Store.ReassignPRReviewer(ctx, prID, oldUserID, func(choice *store.ReviewerChoice) (string, error) {
+		return s.chooseReplacement(choice, ReassignOptions{NewUserID: opts.NewUserID, Mode: mode}, "reassign:")
+	})
+	if err == nil {
+		s.observer.ObserveAssignment("reassign", 1, nil)
+	} else if err.Error() == "NO_CANDIDATE" {
+		s.observer.ObserveAssignment("reassign", 0, err)
+	}
+	if err == nil {
+		logging.FromContext(ctx).Info("reviewer reassigned", "pull_request_id", prID,
+			"old_reviewer_id", oldUserID, "new_reviewer_id", newUserID, "mode", mode, "seed", s.seed)
+	}
+	return newUserID, err
+}
+
+// chooseReplacement picks who takes over the old reviewer's review among the
+// candidates of choice, applying its rules to the reviewers that stay. The
+// random order is derived from keyPrefix, the PR id and the old reviewer. It
+// only uses what the store loaded into choice, as it runs under the PR lock.
+func (s *Service) chooseReplacement(choice *store.ReviewerChoice, opts ReassignOptions, keyPrefix string) (string, error) {
+	pr := choice.PR
+	rules := newRuleSet(choice.Rules, pr.AuthorID)
+	kept := choice.Kept
+	eligible := rules.filter(choice.Candidates)
+
+	if opts.NewUserID != "" {
+		for _, candidate := range eligible {
+			if candidate.UserID == opts.NewUserID {
+				if picked := rules.pick([]*models.User{candidate}, kept, 1); len(picked) > 0 {
+					return picked[0].UserID, nil
+				}
+				break
+			}
 		}
+		return "", errors.New("CANDIDATE_NOT_ELIGIBLE")
 	}
 
-	if len(availableCandidates) == 0 {
+	ordered := shuffleUsers(s.randFor(keyPrefix+pr.PullRequestID+":"+choice.OldUser.UserID), eligible)
+	if opts.Mode == AssignmentModeSkills {
+		ordered = rankBySkills(ordered, pr.Labels, choice.OpenReviews)
+	}
+
+	picked := rules.pick(ordered, kept, 1)
+	if len(picked) == 0 {
 		return "", errors.New("NO_CANDIDATE")
 	}
 
-	newReviewer := availableCandidates[rand.Intn(len(availableCandidates))]
+	return picked[0].UserID, nil
+}
 
-	newReviewers := make([]string, 0, len(pr.AssignedReviewers))
-	for _, reviewer := range pr.AssignedReviewers {
-		if reviewer == oldUserID {
-			newReviewers = append(newReviewers, newReviewer.UserID)
-		} else {
-			newReviewers = append(newReviewers, reviewer)
-		}
+func (s *Service) AddPRReviewer(ctx context.Context, prID, userID string) error {
+	ctx, span := tracing.Start(ctx, "service.AddPRReviewer")
+	defer span.End()
+
+	pr, err := s.Store.GetPR(ctx, prID)
+	if err != nil {
+		return err
+	}
+	user, err := s.Store.GetUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+	author, err := s.Store.GetUser(ctx, pr.AuthorID)
+	if err != nil {
+		return err
+	}
+	if user.TeamName != author.TeamName {
+		return errors.New("NOT_TEAM_MEMBER")
 	}
 
-	err = s.Store.UpdatePRReviewers(prID, newReviewers)
+	rules, err := s.loadRules(ctx, author.TeamName, author.UserID)
 	if err != nil {
-		return "", err
+		return err
+	}
+	kept := make([]*models.User, 0, len(pr.AssignedReviewers))
+	for _, reviewerID := range pr.AssignedReviewers {
+		reviewer, err := s.Store.GetUser(ctx, reviewerID)
+		if err != nil {
+			return err
+		}
+		kept = append(kept, reviewer)
+	}
+	if len(rules.filter([]*models.User{user})) == 0 || len(rules.pick([]*models.User{user}, kept, 1)) == 0 {
+		return errors.New("CANDIDATE_NOT_ELIGIBLE")
 	}
 
-	return newReviewer.UserID, nil
+	return s.Store.AddPRReviewer(ctx, prID, userID)
 }
 
-func shuffleUsers(users []*models.User) []*models.User {
+func shuffleUsers(rng *rand.Rand, users []*models.User) []*models.User {
 	shuffled := make([]*models.User, len(users))
 	copy(shuffled, users)
-	rand.Shuffle(len(shuffled), func(i, j int) {
+	rng.Shuffle(len(shuffled), func(i, j int) {
 		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
 	})
 	return shuffled
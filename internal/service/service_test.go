@@ -0,0 +1,253 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
+)
+
+// The chooser runs under the PR lock, so it must decide from the choice alone.
+// A nil Store makes any store call panic.
+func TestChooseReplacementUsesOnlyChoice(t *testing.T) {
+	s := NewService(nil, 1, nil)
+	choice := &store.ReviewerChoice{
+		PR:      &models.PullRequest{PullRequestID: "pr-1", AuthorID: "author", AssignedReviewers: []string{"old", "kept"}, Labels: []string{"go"}},
+		OldUser: &models.User{UserID: "old"},
+		Candidates: []*models.User{
+			{UserID: "busy", IsActive: true, Skills: []string{"go"}},
+			{UserID: "excluded", IsActive: true, Skills: []string{"go"}},
+			{UserID: "idle", IsActive: true, Skills: []string{"go"}},
+		},
+		Rules: []*models.TeamRule{
+			{ID: 1, Type: RuleExclude, AuthorID: "author", ReviewerID: "excluded"},
+		},
+		Kept:        []*models.User{{UserID: "kept"}},
+		OpenReviews: map[string]int{"busy": 5},
+	}
+
+	newUserID, err := s.chooseReplacement(choice, ReassignOptions{Mode: AssignmentModeSkills}, "reassign:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newUserID != "idle" {
+		t.Fatalf("new reviewer = %q, want the least loaded eligible candidate", newUserID)
+	}
+
+	choice.Candidates = choice.Candidates[1:2]
+	if _, err := s.chooseReplacement(choice, ReassignOptions{Mode: AssignmentModeRandom}, "reassign:"); err == nil || err.Error() != "NO_CANDIDATE" {
+		t.Fatalf("err = %v, want NO_CANDIDATE", err)
+	}
+}
+
+// addReviewerStore serves the reads of AddPRReviewer from maps and records
+// the users the store was asked to assign.
+type addReviewerStore struct {
+	store.Store
+	pr    *models.PullRequest
+	users map[string]*models.User
+	rules []*models.TeamRule
+	added []string
+}
+
+func (f *addReviewerStore) GetPR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	return f.pr, nil
+}
+
+func (f *addReviewerStore) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	if user, ok := f.users[userID]; ok {
+		return user, nil
+	}
+	return nil, errors.New("NOT_FOUND")
+}
+
+func (f *addReviewerStore) GetTeamRules(ctx context.Context, teamName string) ([]*models.TeamRule, error) {
+	return f.rules, nil
+}
+
+func (f *addReviewerStore) AddPRReviewer(ctx context.Context, prID, userID string) error {
+	f.added = append(f.added, userID)
+	return nil
+}
+
+func TestAddPRReviewerAppliesTeamAndRules(t *testing.T) {
+	fake := &addReviewerStore{
+		pr: &models.PullRequest{PullRequestID: "pr-1", AuthorID: "author", AssignedReviewers: []string{"middle"}},
+		users: map[string]*models.User{
+			"author":   {UserID: "author", TeamName: "backend"},
+			"middle":   {UserID: "middle", TeamName: "backend", Seniority: SeniorityMiddle},
+			"excluded": {UserID: "excluded", TeamName: "backend"},
+			"junior":   {UserID: "junior", TeamName: "backend", Seniority: SeniorityJunior},
+			"outsider": {UserID: "outsider", TeamName: "frontend"},
+			"ok":       {UserID: "ok", TeamName: "backend"},
+		},
+		rules: []*models.TeamRule{
+			{ID: 1, Type: RuleExclude, AuthorID: "author", ReviewerID: "excluded"},
+			{ID: 2, Type: RulePairJuniorSenior},
+		},
+	}
+	s := NewService(fake, 1, nil)
+
+	for userID, want := range map[string]string{
+		"outsider": "NOT_TEAM_MEMBER",
+		"excluded": "CANDIDATE_NOT_ELIGIBLE",
+		"junior":   "CANDIDATE_NOT_ELIGIBLE",
+		"missing":  "NOT_FOUND",
+	} {
+		if err := s.AddPRReviewer(context.Background(), "pr-1", userID); err == nil || err.Error() != want {
+			t.Errorf("add %s: err = %v, want %s", userID, err, want)
+		}
+	}
+	if len(fake.added) != 0 {
+		t.Fatalf("store called for rejected reviewers %v", fake.added)
+	}
+
+	if err := s.AddPRReviewer(context.Background(), "pr-1", "ok"); err != nil {
+		t.Fatal(err)
+	}
+	if len(fake.added) != 1 || fake.added[0] != "ok" {
+		t.Fatalf("added = %v, want [ok]", fake.added)
+	}
+}
+
+type reassignStore struct {
+	store.Store
+	err error
+}
+
+func (f reassignStore) ReassignPRReviewer(ctx context.Context, prID, oldUserID string, choose store.ReviewerChooser) (string, error) {
+	if f.err != nil {
+		return "", f.err
+	}
+	return "u3", nil
+}
+
+func TestReassignReviewerReportsToObserver(t *testing.T) {
+	tests := []struct {
+		name         string
+		err          error
+		wantObserved bool
+		wantAssigned int
+	}{
+		{name: "assigned", wantObserved: true, wantAssigned: 1},
+		{name: "no candidate", err: errors.New("NO_CANDIDATE"), wantObserved: true, wantAssigned: 0},
+		{name: "not assigned", err: errors.New("NOT_ASSIGNED")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var observed []string
+			observer := AssignmentObserverFunc(func(operation string, assigned int, err error) {
+				observed = append(observed, operation)
+				if assigned != tt.wantAssigned || err != tt.err {
+					t.Errorf("observed %s: %d, %v; want %d, %v", operation, assigned, err, tt.wantAssigned, tt.err)
+				}
+			})
+			s := NewService(reassignStore{err: tt.err}, 1, observer)
+
+			if _, err := s.ReassignReviewer(context.Background(), "pr-1", "u2", ReassignOptions{}); err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+			if tt.wantObserved && (len(observed) != 1 || observed[0] != "reassign") {
+				t.Fatalf("observed %v, want [reassign]", observed)
+			}
+			if !tt.wantObserved && len(observed) != 0 {
+				t.Fatalf("observed %v, want nothing", observed)
+			}
+		})
+	}
+}
+
+// teamStore serves one team's members, rules and PRs from memory, enough
+// for reviewer selection.
+type teamStore struct {
+	store.Store
+	team        *models.Team
+	users       []*models.User
+	rules       []*models.TeamRule
+	prs         map[string]*models.PullRequest
+	openReviews map[string]int
+}
+
+func newTeamStore(maxReviewers int, users ...*models.User) *teamStore {
+	for _, user := range users {
+		user.TeamName = "backend"
+	}
+	return &teamStore{
+		team:  &models.Team{TeamName: "backend", MaxReviewers: maxReviewers},
+		users: users,
+		prs:   make(map[string]*models.PullRequest),
+	}
+}
+
+func (f *teamStore) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	for _, user := range f.users {
+		if user.UserID == userID {
+			return user, nil
+		}
+	}
+	return nil, errors.New("NOT_FOUND")
+}
+
+func (f *teamStore) GetTeam(ctx context.Context, teamName string) (*models.Team, error) {
+	return f.team, nil
+}
+
+func (f *teamStore) GetTeamUsers(ctx context.Context, teamName string) ([]*models.User, error) {
+	return f.users, nil
+}
+
+func (f *teamStore) GetTeamRules(ctx context.Context, teamName string) ([]*models.TeamRule, error) {
+	return f.rules, nil
+}
+
+func (f *teamStore) GetPR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	if pr, ok := f.prs[prID]; ok {
+		return pr, nil
+	}
+	return nil, errors.New("NOT_FOUND")
+}
+
+func (f *teamStore) GetOpenReviewCounts(ctx context.Context, userIDs []string) (map[string]int, error) {
+	return f.openReviews, nil
+}
+
+func activeUsers(ids ...string) []*models.User {
+	users := make([]*models.User, len(ids))
+	for i, id := range ids {
+		users[i] = &models.User{UserID: id, IsActive: true}
+	}
+	return users
+}
+
+func TestAssignReviewersIsReproducibleFromSeed(t *testing.T) {
+	fake := newTeamStore(2, activeUsers("author", "u1", "u2", "u3", "u4", "u5", "u6")...)
+	assign := func(seed int64, prID string) string {
+		t.Helper()
+		reviewers, err := NewService(fake, seed, nil).AssignReviewers(context.Background(), "author",
+			AssignOptions{PullRequestID: prID, Mode: AssignmentModeRandom})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(reviewers) != 2 {
+			t.Fatalf("%s: reviewers = %v, want two", prID, reviewers)
+		}
+		return strings.Join(reviewers, ",")
+	}
+
+	seen := make(map[string]bool)
+	for i := 1; i <= 10; i++ {
+		prID := fmt.Sprintf("pr-%d", i)
+		first := assign(42, prID)
+		if again := assign(42, prID); again != first {
+			t.Fatalf("%s: seed 42 picked %s, then %s", prID, first, again)
+		}
+		seen[first] = true
+	}
+	if len(seen) < 2 {
+		t.Fatalf("every PR got the same reviewers %v", seen)
+	}
+}
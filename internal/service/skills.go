@@ -0,0 +1,80 @@
+package service
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"pr-reviewer/internal/models"
+)
+
+// Weights used by the skills assignment mode. A matching tag is worth more
+// than one open review so that expertise wins over load on close calls.
+const (
+	skillMatchWeight = 3
+	openReviewWeight = 1
+)
+
+// NormalizeTags lowercases, trims and de-duplicates skill tags and PR labels
+// so that "Go", " go" and "GO" all match each other.
+func NormalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	normalized := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	sort.Strings(normalized)
+	return normalized
+}
+
+// rankBySkillsWithLoad ranks candidates as rankBySkills, reading their open
+// review load from the store unless load is given.
+func (s *Service) rankBySkillsWithLoad(ctx context.Context, candidates []*models.User, labels []string, load reviewLoad) ([]*models.User, error) {
+	if load == nil {
+		ids := make([]string, len(candidates))
+		for i, candidate := range candidates {
+			ids[i] = candidate.UserID
+		}
+
+		counts, err := s.Store.GetOpenReviewCounts(ctx, ids)
+		if err != nil {
+			return nil, err
+		}
+		load = counts
+	}
+	return rankBySkills(candidates, labels, load), nil
+}
+
+// rankBySkills orders candidates by tag overlap with the PR labels minus
+// their open review load. The input order is kept for ties, so callers
+// should shuffle beforehand to break ties randomly.
+func rankBySkills(candidates []*models.User, labels []string, load map[string]int) []*models.User {
+	wanted := make(map[string]bool, len(labels))
+	for _, label := range NormalizeTags(labels) {
+		wanted[label] = true
+	}
+
+	scores := make(map[string]int, len(candidates))
+	for _, candidate := range candidates {
+		overlap := 0
+		for _, skill := range NormalizeTags(candidate.Skills) {
+			if wanted[skill] {
+				overlap++
+			}
+		}
+		scores[candidate.UserID] = overlap*skillMatchWeight - load[candidate.UserID]*openReviewWeight
+	}
+
+	ranked := make([]*models.User, len(candidates))
+	copy(ranked, candidates)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i].UserID] > scores[ranked[j].UserID]
+	})
+
+	return ranked
+}
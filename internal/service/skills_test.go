@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"pr-reviewer/internal/models"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name string
+		tags []string
+		want []string
+	}{
+		{"nil", nil, []string{}},
+		{"case and spaces", []string{" Go", "GO ", "go", "Postgres"}, []string{"go", "postgres"}},
+		{"blank tags dropped", []string{"", "  ", "api"}, []string{"api"}},
+		{"sorted", []string{"sql", "api", "go"}, []string{"api", "go", "sql"}},
+	}
+	for _, tt := range tests {
+		if got := NormalizeTags(tt.tags); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: NormalizeTags(%q) = %q, want %q", tt.name, tt.tags, got, tt.want)
+		}
+	}
+}
+
+func TestRankBySkills(t *testing.T) {
+	skilled := func(id string, skills ...string) *models.User {
+		return &models.User{UserID: id, Skills: skills, IsActive: true}
+	}
+
+	tests := []struct {
+		name       string
+		candidates []*models.User
+		labels     []string
+		load       map[string]int
+		want       []string
+	}{
+		{"a match beats no match", []*models.User{skilled("none"), skilled("match", "go")}, []string{"go"}, nil, []string{"match", "none"}},
+		{"labels match case-insensitively", []*models.User{skilled("none"), skilled("match", "Go ")}, []string{" GO"}, nil, []string{"match", "none"}},
+		{"more matches rank higher", []*models.User{skilled("one", "go"), skilled("two", "go", "sql")}, []string{"go", "sql"}, nil, []string{"two", "one"}},
+		{"equal matches go to the least loaded", []*models.User{skilled("busy", "go"), skilled("idle", "go")}, []string{"go"}, map[string]int{"busy": 1}, []string{"idle", "busy"}},
+		{"a match outweighs two open reviews", []*models.User{skilled("none"), skilled("match", "go")}, []string{"go"}, map[string]int{"match": 2}, []string{"match", "none"}},
+		{"a match equals three open reviews", []*models.User{skilled("none"), skilled("match", "go")}, []string{"go"}, map[string]int{"match": 3}, []string{"none", "match"}},
+		{"four open reviews outweigh a match", []*models.User{skilled("match", "go"), skilled("none")}, []string{"go"}, map[string]int{"match": 4}, []string{"none", "match"}},
+		{"ties keep the input order", []*models.User{skilled("b", "go"), skilled("a", "go"), skilled("c", "go")}, []string{"go"}, nil, []string{"b", "a", "c"}},
+		{"no labels rank by load", []*models.User{skilled("busy", "go"), skilled("idle")}, nil, map[string]int{"busy": 2}, []string{"idle", "busy"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := userIDs(rankBySkills(tt.candidates, tt.labels, tt.load))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ranked %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankBySkillsWithLoadReadsStore(t *testing.T) {
+	fake := newTeamStore(2)
+	fake.openReviews = map[string]int{"busy": 1}
+	s := NewService(fake, 1, nil)
+	candidates := []*models.User{{UserID: "busy", Skills: []string{"go"}}, {UserID: "idle", Skills: []string{"go"}}}
+
+	ranked, err := s.rankBySkillsWithLoad(context.Background(), candidates, []string{"go"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := userIDs(ranked); !reflect.DeepEqual(got, []string{"idle", "busy"}) {
+		t.Errorf("with stored load: %v, want [idle busy]", got)
+	}
+
+	ranked, err = s.rankBySkillsWithLoad(context.Background(), candidates, []string{"go"}, reviewLoad{"idle": 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := userIDs(ranked); !reflect.DeepEqual(got, []string{"busy", "idle"}) {
+		t.Errorf("with given load: %v, want [busy idle]", got)
+	}
+}
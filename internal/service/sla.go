@@ -0,0 +1,53 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/tracing"
+)
+
+func (s *Service) RecordReviewDecision(ctx context.Context, prID, userID, decision string) error {
+	ctx, span := tracing.Start(ctx, "service.RecordReviewDecision")
+	defer span.End()
+
+	switch decision {
+	case models.DecisionApproved, models.DecisionChangesRequested, models.DecisionCommented:
+	default:
+		return errors.New("INVALID_DECISION")
+	}
+	return s.Store.RecordReviewDecision(ctx, prID, userID, decision)
+}
+
+// OverdueReviews returns pending reviews that have waited longer than their
+// team's SLA as of now, oldest first. Waiting time is counted in the
+// reviewer's business hours when they have a work schedule.
+func (s *Service) OverdueReviews(ctx context.Context, teamName string, now time.Time) ([]*models.PendingReview, error) {
+	ctx, span := tracing.Start(ctx, "service.OverdueReviews")
+	defer span.End()
+
+	pending, err := s.Store.GetPendingReviews(ctx, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	overdue := make([]*models.PendingReview, 0, len(pending))
+	for _, review := range pending {
+		waited := BusinessDuration(review.ReviewerSchedule, review.AssignedAt, now)
+		sla := time.Duration(review.SLAHours) * time.Hour
+		if waited <= sla {
+			continue
+		}
+		review.WaitingHours = roundHours(waited)
+		review.OverdueHours = roundHours(waited - sla)
+		overdue = append(overdue, review)
+	}
+
+	return overdue, nil
+}
+
+func roundHours(d time.Duration) float64 {
+	return float64(d.Round(time.Minute)) / float64(time.Hour)
+}
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
+)
+
+type pendingStore struct {
+	store.Store
+	pending []*models.PendingReview
+}
+
+func (f pendingStore) GetPendingReviews(ctx context.Context, teamName string) ([]*models.PendingReview, error) {
+	return f.pending, nil
+}
+
+func TestOverdueReviewsAtSLABoundary(t *testing.T) {
+	// Monday noon.
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	office := &models.WorkSchedule{Timezone: "UTC", Start: "09:00", End: "17:00", Days: defaultWorkDays}
+	weekends := &models.WorkSchedule{Timezone: "UTC", Start: "09:00", End: "17:00", Days: []string{"sat", "sun"}}
+
+	tests := []struct {
+		name        string
+		assignedAt  time.Time
+		schedule    *models.WorkSchedule
+		wantOverdue float64
+		wantWaiting float64
+	}{
+		{name: "exactly at the SLA", assignedAt: now.Add(-8 * time.Hour)},
+		{name: "a minute past the SLA", assignedAt: now.Add(-8*time.Hour - time.Minute), wantOverdue: 1.0 / 60, wantWaiting: 8 + 1.0/60},
+		{name: "over the weekend within business hours", assignedAt: now.Add(-72 * time.Hour), schedule: office},
+		{name: "over the weekend past business hours", assignedAt: now.Add(-73 * time.Hour), schedule: office, wantOverdue: 1, wantWaiting: 9},
+		{name: "outside the reviewer's working days", assignedAt: now.Add(-12 * time.Hour), schedule: weekends},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			review := &models.PendingReview{PullRequestID: "pr-1", AssignedAt: tt.assignedAt, SLAHours: 8, ReviewerSchedule: tt.schedule}
+			s := NewService(pendingStore{pending: []*models.PendingReview{review}}, 1, nil)
+
+			overdue, err := s.OverdueReviews(context.Background(), "backend", now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantOverdue == 0 {
+				if len(overdue) != 0 {
+					t.Fatalf("overdue = %+v, want none", overdue[0])
+				}
+				return
+			}
+			if len(overdue) != 1 || overdue[0].OverdueHours != tt.wantOverdue || overdue[0].WaitingHours != tt.wantWaiting {
+				t.Fatalf("overdue = %+v, want %vh overdue after %vh", overdue, tt.wantOverdue, tt.wantWaiting)
+			}
+		})
+	}
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"pr-reviewer/internal/logging"
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/store"
+	"pr-reviewer/internal/tracing"
+)
+
+// What happens to the OPEN reviews of a user moved to another team.
+const (
+	ReviewPolicyReassign = "reassign"
+	ReviewPolicyKeep     = "keep"
+)
+
+// NormalizeTeamMembers validates seniorities and normalizes skills in place.
+func NormalizeTeamMembers(members []models.TeamMember) error {
+	for i := range members {
+		if !ValidSeniority(members[i].Seniority) {
+			return errors.New("INVALID_SENIORITY")
+		}
+		members[i].Skills = NormalizeTags(members[i].Skills)
+	}
+	return nil
+}
+
+func (s *Service) AddTeamMembers(ctx context.Context, teamName string, members []models.TeamMember) (*models.Team, error) {
+	ctx, span := tracing.Start(ctx, "service.AddTeamMembers")
+	defer span.End()
+
+	if err := NormalizeTeamMembers(members); err != nil {
+		return nil, err
+	}
+	if err := s.Store.AddTeamMembers(ctx, teamName, members); err != nil {
+		return nil, err
+	}
+	return s.Store.GetTeam(ctx, teamName)
+}
+
+// RemoveTeamMember removes userID from teamName and hands each of their OPEN
+// reviews to an eligible member of the PR author's team.
+func (s *Service) RemoveTeamMember(ctx context.Context, teamName, userID string) ([]*models.ReviewHandoff, error) {
+	ctx, span := tracing.Start(ctx, "service.RemoveTeamMember")
+	defer span.End()
+
+	return s.Store.RemoveTeamMember(ctx, teamName, userID, s.handoffChooser(ctx))
+}
+
+// MoveTeamMember moves userID into teamName. With ReviewPolicyReassign (the
+// default) their OPEN reviews are handed off as in RemoveTeamMember; with
+// ReviewPolicyKeep they stay assigned.
+func (s *Service) MoveTeamMember(ctx context.Context, userID, teamName, policy string) ([]*models.ReviewHandoff, error) {
+	ctx, span := tracing.Start(ctx, "service.MoveTeamMember")
+	defer span.End()
+
+	switch policy {
+	case "", ReviewPolicyReassign:
+		return s.Store.MoveTeamMember(ctx, userID, teamName, s.handoffChooser(ctx))
+	case ReviewPolicyKeep:
+		return s.Store.MoveTeamMember(ctx, userID, teamName, nil)
+	default:
+		return nil, errors.New("INVALID_POLICY")
+	}
+}
+
+// DeleteTeam deletes a team without OPEN PRs by its members and hands off
+// the members' OPEN reviews on other teams' PRs.
+func (s *Service) DeleteTeam(ctx context.Context, teamName string) ([]*models.ReviewHandoff, error) {
+	ctx, span := tracing.Start(ctx, "service.DeleteTeam")
+	defer span.End()
+
+	return s.Store.DeleteTeam(ctx, teamName, s.handoffChooser(ctx))
+}
+
+// handoffChooser picks replacements for a reviewer giving up their reviews;
+// the store loads the candidates and rules of the PR author's team. Reviews
+// without an eligible candidate are released without a replacement instead
+// of failing the whole change.
+func (s *Service) handoffChooser(ctx context.Context) store.ReviewerChooser {
+	return func(choice *store.ReviewerChoice) (string, error) {
+		newUserID, err := s.chooseReplacement(choice, ReassignOptions{Mode: AssignmentModeRandom}, "handoff:")
+		if err != nil && err.Error() == "NO_CANDIDATE" {
+			logging.FromContext(ctx).Warn("review released without replacement", "pull_request_id", choice.PR.PullRequestID, "reviewer_id", choice.OldUser.UserID)
+			return "", nil
+		}
+		return newUserID, err
+	}
+}
+
+// SyncTeams makes teams the complete set of teams and members. Every team
+// must be named and every user listed once; an empty set is rejected so a
+// truncated directory file cannot remove everyone.
+func (s *Service) SyncTeams(ctx context.Context, teams []models.Team, dryRun bool) (*models.TeamSyncDiff, error) {
+	ctx, span := tracing.Start(ctx, "service.SyncTeams")
+	defer span.End()
+
+	if len(teams) == 0 {
+		return nil, errors.New("INVALID_SYNC")
+	}
+
+	teamNames := make(map[string]bool, len(teams))
+	userIDs := make(map[string]bool)
+	for i := range teams {
+		if teams[i].TeamName == "" || teamNames[teams[i].TeamName] {
+			return nil, errors.New("INVALID_SYNC")
+		}
+		teamNames[teams[i].TeamName] = true
+
+		if teams[i].StaleAction != "" && teams[i].StaleAction != models.StaleActionReassign && teams[i].StaleAction != models.StaleActionAddBackup {
+			return nil, errors.New("INVALID_ESCALATION")
+		}
+		for _, member := range teams[i].Members {
+			if member.UserID == "" || userIDs[member.UserID] {
+				return nil, errors.New("INVALID_SYNC")
+			}
+			userIDs[member.UserID] = true
+		}
+		if err := NormalizeTeamMembers(teams[i].Members); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.Store.SyncTeams(ctx, teams, dryRun, s.handoffChooser(ctx))
+}
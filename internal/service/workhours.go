@@ -0,0 +1,202 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+	_ "time/tzdata"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/tracing"
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+var defaultWorkDays = []string{"mon", "tue", "wed", "thu", "fri"}
+
+// workWindow is a parsed models.WorkSchedule.
+type workWindow struct {
+	loc   *time.Location
+	start time.Duration
+	end   time.Duration
+	days  map[time.Weekday]bool
+}
+
+// ValidateSchedule checks a working-hours schedule and fills in the default
+// work days (Monday to Friday) when none are given. Windows crossing midnight
+// are not supported.
+func ValidateSchedule(schedule *models.WorkSchedule) error {
+	if schedule == nil {
+		return nil
+	}
+	if len(schedule.Days) == 0 {
+		schedule.Days = defaultWorkDays
+	}
+	_, err := parseSchedule(schedule)
+	return err
+}
+
+func parseSchedule(schedule *models.WorkSchedule) (*workWindow, error) {
+	if schedule.Timezone == "" {
+		return nil, errors.New("INVALID_SCHEDULE")
+	}
+	loc, err := loadLocation(schedule.Timezone)
+	if err != nil {
+		return nil, errors.New("INVALID_SCHEDULE")
+	}
+
+	start, err := parseClock(schedule.Start)
+	if err != nil {
+		return nil, err
+	}
+	end, err := parseClock(schedule.End)
+	if err != nil {
+		return nil, err
+	}
+	if end <= start {
+		return nil, errors.New("INVALID_SCHEDULE")
+	}
+
+	days := schedule.Days
+	if len(days) == 0 {
+		days = defaultWorkDays
+	}
+	window := &workWindow{loc: loc, start: start, end: end, days: make(map[time.Weekday]bool, len(days))}
+	for _, day := range days {
+		weekday, ok := weekdays[day]
+		if !ok {
+			return nil, errors.New("INVALID_SCHEDULE")
+		}
+		window.days[weekday] = true
+	}
+
+	return window, nil
+}
+
+// locations caches time.LoadLocation, which reads and parses the zone data
+// on every call. Only valid names are cached, so the cache is bounded by the
+// zone database.
+var locations sync.Map
+
+func loadLocation(name string) (*time.Location, error) {
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+func parseClock(value string) (time.Duration, error) {
+	if value == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, errors.New("INVALID_SCHEDULE")
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// InWorkingHours reports whether t falls into the schedule. Users without a
+// schedule, or with one that no longer parses, are always available.
+func InWorkingHours(schedule *models.WorkSchedule, t time.Time) bool {
+	if schedule == nil {
+		return true
+	}
+	window, err := parseSchedule(schedule)
+	if err != nil {
+		return true
+	}
+
+	local := t.In(window.loc)
+	if !window.days[local.Weekday()] {
+		return false
+	}
+	clock := time.Duration(local.Hour())*time.Hour + time.Duration(local.Minute())*time.Minute +
+		time.Duration(local.Second())*time.Second
+	return clock >= window.start && clock < window.end
+}
+
+// BusinessDuration is the part of [from, to) that falls into the schedule's
+// working hours. Without a schedule it is plain wall-clock time.
+func BusinessDuration(schedule *models.WorkSchedule, from, to time.Time) time.Duration {
+	if !to.After(from) {
+		return 0
+	}
+	if schedule == nil {
+		return to.Sub(from)
+	}
+	window, err := parseSchedule(schedule)
+	if err != nil {
+		return to.Sub(from)
+	}
+
+	var total time.Duration
+	for day := midnight(from.In(window.loc)); day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !window.days[day.Weekday()] {
+			continue
+		}
+		open := atClock(day, window.start)
+		closed := atClock(day, window.end)
+		if open.Before(from) {
+			open = from
+		}
+		if closed.After(to) {
+			closed = to
+		}
+		if closed.After(open) {
+			total += closed.Sub(open)
+		}
+	}
+
+	return total
+}
+
+func midnight(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// atClock returns the wall-clock time of day on day's date, which stays
+// correct across daylight saving changes unlike adding a duration to midnight.
+func atClock(day time.Time, clock time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		int(clock/time.Hour), int(clock%time.Hour/time.Minute), 0, 0, day.Location())
+}
+
+// preferWorkingHours moves candidates who are currently working ahead of the
+// rest, keeping the existing order within both groups.
+func preferWorkingHours(candidates []*models.User, now time.Time) []*models.User {
+	ordered := make([]*models.User, 0, len(candidates))
+	var offHours []*models.User
+	for _, candidate := range candidates {
+		if InWorkingHours(candidate.WorkSchedule, now) {
+			ordered = append(ordered, candidate)
+		} else {
+			offHours = append(offHours, candidate)
+		}
+	}
+	return append(ordered, offHours...)
+}
+
+func (s *Service) SetUserSchedule(ctx context.Context, userID string, schedule *models.WorkSchedule) (*models.User, error) {
+	ctx, span := tracing.Start(ctx, "service.SetUserSchedule")
+	defer span.End()
+
+	if err := ValidateSchedule(schedule); err != nil {
+		return nil, err
+	}
+	return s.Store.UpdateUserSchedule(ctx, userID, schedule)
+}
@@ -0,0 +1,124 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/models"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestInWorkingHours(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	office := &models.WorkSchedule{Timezone: "Europe/Berlin", Start: "09:00", End: "17:00"}
+	evening := &models.WorkSchedule{Timezone: "Europe/Berlin", Start: "22:00", End: "24:00", Days: []string{"mon"}}
+
+	tests := []struct {
+		name     string
+		schedule *models.WorkSchedule
+		at       time.Time
+		want     bool
+	}{
+		{"no schedule", nil, time.Date(2026, 10, 18, 3, 0, 0, 0, berlin), true},
+		{"opening time", office, time.Date(2026, 10, 19, 9, 0, 0, 0, berlin), true},
+		{"closing time", office, time.Date(2026, 10, 19, 17, 0, 0, 0, berlin), false},
+		{"in another zone", office, time.Date(2026, 10, 19, 7, 30, 0, 0, time.UTC), true},
+		{"saturday", office, time.Date(2026, 10, 17, 12, 0, 0, 0, berlin), false},
+		{"sunday", office, time.Date(2026, 10, 18, 12, 0, 0, 0, berlin), false},
+		{"just before midnight", evening, time.Date(2026, 10, 19, 23, 59, 59, 0, berlin), true},
+		{"midnight ends the day", evening, time.Date(2026, 10, 20, 0, 0, 0, 0, berlin), false},
+		{"after the DST switch", office, time.Date(2026, 3, 30, 8, 30, 0, 0, time.UTC), true},
+		{"before the DST switch", office, time.Date(2026, 3, 27, 8, 30, 0, 0, time.UTC), true},
+		{"DST shifts the UTC hours", office, time.Date(2026, 3, 30, 15, 30, 0, 0, time.UTC), false},
+		{"invalid zone", &models.WorkSchedule{Timezone: "Mars/Olympus", Start: "09:00", End: "17:00"}, time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC), true},
+		{"empty zone", &models.WorkSchedule{Start: "09:00", End: "17:00"}, time.Date(2026, 10, 18, 3, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		if got := InWorkingHours(tt.schedule, tt.at); got != tt.want {
+			t.Errorf("%s: InWorkingHours = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBusinessDuration(t *testing.T) {
+	berlin := mustLoad(t, "Europe/Berlin")
+	office := &models.WorkSchedule{Timezone: "Europe/Berlin", Start: "09:00", End: "17:00"}
+	allDay := &models.WorkSchedule{Timezone: "Europe/Berlin", Start: "00:00", End: "24:00", Days: []string{"sat", "sun", "mon"}}
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2026, month, day, hour, 0, 0, 0, berlin)
+	}
+
+	tests := []struct {
+		name     string
+		schedule *models.WorkSchedule
+		from, to time.Time
+		want     time.Duration
+	}{
+		{"no schedule", nil, at(10, 16, 16), at(10, 19, 10), 66 * time.Hour},
+		{"empty range", office, at(10, 19, 12), at(10, 19, 12), 0},
+		{"reversed range", office, at(10, 19, 12), at(10, 19, 10), 0},
+		{"within a day", office, at(10, 19, 10), at(10, 19, 12), 2 * time.Hour},
+		{"outside hours", office, at(10, 19, 18), at(10, 20, 8), 0},
+		{"over the weekend", office, at(10, 16, 16), at(10, 19, 10), 2 * time.Hour},
+		{"full weeks", office, at(10, 12, 0), at(10, 26, 0), 80 * time.Hour},
+		{"across midnight", allDay, at(10, 18, 23), at(10, 19, 1), 2 * time.Hour},
+		{"short DST day", allDay, at(3, 29, 0), at(3, 30, 0), 23 * time.Hour},
+		{"long DST day", allDay, at(10, 25, 0), at(10, 26, 0), 25 * time.Hour},
+		{"office hours on DST week", office, at(3, 27, 0), at(3, 31, 0), 16 * time.Hour},
+		{"invalid zone", &models.WorkSchedule{Timezone: "Mars/Olympus", Start: "09:00", End: "17:00"}, at(10, 16, 16), at(10, 19, 10), 66 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := BusinessDuration(tt.schedule, tt.from, tt.to); got != tt.want {
+			t.Errorf("%s: BusinessDuration = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule models.WorkSchedule
+		valid    bool
+	}{
+		{"office hours", models.WorkSchedule{Timezone: "Asia/Tokyo", Start: "09:00", End: "18:00"}, true},
+		{"until midnight", models.WorkSchedule{Timezone: "UTC", Start: "20:00", End: "24:00"}, true},
+		{"unknown zone", models.WorkSchedule{Timezone: "Mars/Olympus", Start: "09:00", End: "17:00"}, false},
+		{"missing zone", models.WorkSchedule{Start: "09:00", End: "17:00"}, false},
+		{"crossing midnight", models.WorkSchedule{Timezone: "UTC", Start: "22:00", End: "02:00"}, false},
+		{"bad clock", models.WorkSchedule{Timezone: "UTC", Start: "9am", End: "17:00"}, false},
+		{"bad day", models.WorkSchedule{Timezone: "UTC", Start: "09:00", End: "17:00", Days: []string{"funday"}}, false},
+	}
+	for _, tt := range tests {
+		err := ValidateSchedule(&tt.schedule)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: err = %v, want valid %v", tt.name, err, tt.valid)
+		}
+		if tt.valid && len(tt.schedule.Days) != 5 {
+			t.Errorf("%s: days = %v, want Monday to Friday by default", tt.name, tt.schedule.Days)
+		}
+	}
+}
+
+func TestLoadLocationCachesValidZones(t *testing.T) {
+	first, err := loadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second, _ := loadLocation("America/New_York"); second != first {
+		t.Error("second load returned a new location")
+	}
+	if _, err := loadLocation("Mars/Olympus"); err == nil {
+		t.Fatal("invalid zone loaded")
+	}
+	if _, ok := locations.Load("Mars/Olympus"); ok {
+		t.Error("invalid zone was cached")
+	}
+}
@@ -0,0 +1,115 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"pr-reviewer/internal/models"
+)
+
+// TestConcurrentReassignAndMerge races many reassigns of the same PR against
+// a merge. The PR row lock must keep the reviewer set consistent: no
+// duplicates, no change after the merge and a history matching the result.
+func TestConcurrentReassignAndMerge(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	team := &models.Team{TeamName: "backend"}
+	for i := 0; i < 8; i++ {
+		id := fmt.Sprintf("u%d", i)
+		team.Members = append(team.Members, models.TeamMember{UserID: id, Username: id, IsActive: true})
+	}
+	if err := s.CreateTeam(ctx, team); err != nil {
+		t.Fatal(err)
+	}
+	pr := &models.PullRequest{PullRequestID: "pr-1", PullRequestName: "race", AuthorID: "u0", AssignedReviewers: []string{"u1", "u2"}}
+	if err := s.CreatePR(ctx, pr); err != nil {
+		t.Fatal(err)
+	}
+
+	first := func(choice *ReviewerChoice) (string, error) {
+		if len(choice.Candidates) == 0 {
+			return "", errors.New("NO_CANDIDATE")
+		}
+		return choice.Candidates[0].UserID, nil
+	}
+
+	const workers, rounds = 16, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*rounds+1)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for r := 0; r < rounds; r++ {
+				oldUserID := fmt.Sprintf("u%d", 1+(w+r)%7)
+				if _, err := s.ReassignPRReviewer(ctx, pr.PullRequestID, oldUserID, first); err != nil {
+					errs <- err
+				}
+				if w == 0 && r == rounds/2 {
+					if err := s.MergePR(ctx, pr.PullRequestID); err != nil {
+						errs <- err
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		switch err.Error() {
+		case "NOT_ASSIGNED", "NO_CANDIDATE", "PR_MERGED":
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	merged, err := s.GetPR(ctx, pr.PullRequestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if merged.Status != "MERGED" {
+		t.Fatalf("status = %s, want MERGED", merged.Status)
+	}
+	if len(merged.AssignedReviewers) != 2 {
+		t.Fatalf("reviewers = %v, want two", merged.AssignedReviewers)
+	}
+	current := make(map[string]bool)
+	for _, id := range merged.AssignedReviewers {
+		if id == pr.AuthorID || current[id] {
+			t.Fatalf("reviewers = %v: author or duplicate assigned", merged.AssignedReviewers)
+		}
+		current[id] = true
+	}
+
+	if _, err := s.ReassignPRReviewer(ctx, pr.PullRequestID, merged.AssignedReviewers[0], first); err == nil || err.Error() != "PR_MERGED" {
+		t.Fatalf("reassign after merge: err = %v, want PR_MERGED", err)
+	}
+
+	history, err := s.GetPRHistory(ctx, pr.PullRequestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	open := make(map[string]bool)
+	for _, entry := range history {
+		if entry.UnassignedAt != nil {
+			continue
+		}
+		if open[entry.UserID] {
+			t.Fatalf("%s has two open assignments", entry.UserID)
+		}
+		open[entry.UserID] = true
+	}
+	if len(open) != len(current) {
+		t.Fatalf("open assignments %v, reviewers %v", open, current)
+	}
+	for id := range current {
+		if !open[id] {
+			t.Fatalf("reviewer %s has no open assignment", id)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"pr-reviewer/internal/models"
+)
+
+func TestAddBackupReviewerKeepsStaleReviewerWithinLimit(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	team := &models.Team{TeamName: "backend", MaxReviewers: 2, Members: []models.TeamMember{
+		{UserID: "u1", Username: "u1", IsActive: true},
+		{UserID: "u2", Username: "u2", IsActive: true},
+		{UserID: "u3", Username: "u3", IsActive: true},
+		{UserID: "u4", Username: "u4", IsActive: true},
+	}}
+	if err := s.CreateTeam(ctx, team); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreatePR(ctx, &models.PullRequest{PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "u1", AssignedReviewers: []string{"u2"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var candidates []string
+	backup, err := s.AddBackupReviewer(ctx, "pr-1", "u2", func(choice *ReviewerChoice) (string, error) {
+		if len(choice.Kept) != 1 || choice.Kept[0].UserID != "u2" {
+			t.Errorf("kept = %v, want the stale reviewer", choice.Kept)
+		}
+		for _, candidate := range choice.Candidates {
+			candidates = append(candidates, candidate.UserID)
+		}
+		return "u3", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backup != "u3" || len(candidates) != 2 || candidates[0] != "u3" || candidates[1] != "u4" {
+		t.Fatalf("backup %s from %v, want u3 from [u3 u4]", backup, candidates)
+	}
+
+	pr, err := s.GetPR(ctx, "pr-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pr.AssignedReviewers) != 2 {
+		t.Fatalf("reviewers = %v, want u2 and u3", pr.AssignedReviewers)
+	}
+
+	_, err = s.AddBackupReviewer(ctx, "pr-1", "u3", func(choice *ReviewerChoice) (string, error) {
+		t.Error("chooser called for a PR at max_reviewers")
+		return "u4", nil
+	})
+	if err == nil || err.Error() != "TOO_MANY_REVIEWERS" {
+		t.Fatalf("err = %v, want TOO_MANY_REVIEWERS", err)
+	}
+}
+
+func TestTryAdvisoryLockElectsOneHolder(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	const key = 42
+
+	unlock, acquired, err := s.TryAdvisoryLock(ctx, key)
+	if err != nil || !acquired {
+		t.Fatalf("first lock: acquired %v, err %v", acquired, err)
+	}
+	if _, acquired, err := s.TryAdvisoryLock(ctx, key); err != nil || acquired {
+		t.Fatalf("second lock while held: acquired %v, err %v", acquired, err)
+	}
+
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	unlock, acquired, err = s.TryAdvisoryLock(ctx, key)
+	if err != nil || !acquired {
+		t.Fatalf("lock after release: acquired %v, err %v", acquired, err)
+	}
+	unlock()
+}
@@ -0,0 +1,299 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"pr-reviewer/internal/models"
+	"pr-reviewer/internal/tracing"
+)
+
+// QueryObserver receives the duration and result of every store call along
+// with the caller's context.
+type QueryObserver func(ctx context.Context, method string, duration time.Duration, err error)
+
+type instrumentedStore struct {
+	inner   Store
+	observe QueryObserver
+}
+
+// Instrument wraps inner so that every method runs in its own span and
+// observe is called afterwards with the method name, so query latency can be
+// reported without the store depending on a metrics library. The time spent
+// in ReviewerChooser callbacks is included in the measured duration.
+func Instrument(inner Store, observe QueryObserver) Store {
+	return &instrumentedStore{inner: inner, observe: observe}
+}
+
+// instrument runs fn, a call to the inner store, in a span named after
+// method and reports its duration and error. The methods below only forward
+// their arguments through instrument, or exec for calls returning an error.
+func instrument[T any](s *instrumentedStore, ctx context.Context, method string, fn func(ctx context.Context) (T, error)) (T, error) {
+	ctx, span := tracing.Start(ctx, "store."+method)
+	defer span.End()
+
+	start := time.Now()
+	result, err := fn(ctx)
+	if IsDatabaseError(err) {
+		tracing.Fail(span, err)
+	}
+	s.observe(ctx, method, time.Since(start), err)
+	return result, err
+}
+
+func (s *instrumentedStore) exec(ctx context.Context, method string, fn func(ctx context.Context) error) error {
+	_, err := instrument(s, ctx, method, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, fn(ctx)
+	})
+	return err
+}
+
+func (s *instrumentedStore) CreateTeam(ctx context.Context, team *models.Team) error {
+	return s.exec(ctx, "CreateTeam", func(ctx context.Context) error {
+		return s.inner.CreateTeam(ctx, team)
+	})
+}
+
+func (s *instrumentedStore) GetTeam(ctx context.Context, teamName string) (*models.Team, error) {
+	return instrument(s, ctx, "GetTeam", func(ctx context.Context) (*models.Team, error) {
+		return s.inner.GetTeam(ctx, teamName)
+	})
+}
+
+func (s *instrumentedStore) UpdateTeamMaxReviewers(ctx context.Context, teamName string, maxReviewers int) error {
+	return s.exec(ctx, "UpdateTeamMaxReviewers", func(ctx context.Context) error {
+		return s.inner.UpdateTeamMaxReviewers(ctx, teamName, maxReviewers)
+	})
+}
+
+func (s *instrumentedStore) UpdateTeamReviewSLA(ctx context.Context, teamName string, slaHours int) error {
+	return s.exec(ctx, "UpdateTeamReviewSLA", func(ctx context.Context) error {
+		return s.inner.UpdateTeamReviewSLA(ctx, teamName, slaHours)
+	})
+}
+
+func (s *instrumentedStore) UpdateTeamEscalation(ctx context.Context, teamName string, staleAfterHours int, action string) error {
+	return s.exec(ctx, "UpdateTeamEscalation", func(ctx context.Context) error {
+		return s.inner.UpdateTeamEscalation(ctx, teamName, staleAfterHours, action)
+	})
+}
+
+func (s *instrumentedStore) AddTeamMembers(ctx context.Context, teamName string, members []models.TeamMember) error {
+	return s.exec(ctx, "AddTeamMembers", func(ctx context.Context) error {
+		return s.inner.AddTeamMembers(ctx, teamName, members)
+	})
+}
+
+func (s *instrumentedStore) RemoveTeamMember(ctx context.Context, teamName, userID string, choose ReviewerChooser) ([]*models.ReviewHandoff, error) {
+	return instrument(s, ctx, "RemoveTeamMember", func(ctx context.Context) ([]*models.ReviewHandoff, error) {
+		return s.inner.RemoveTeamMember(ctx, teamName, userID, choose)
+	})
+}
+
+func (s *instrumentedStore) MoveTeamMember(ctx context.Context, userID, teamName string, choose ReviewerChooser) ([]*models.ReviewHandoff, error) {
+	return instrument(s, ctx, "MoveTeamMember", func(ctx context.Context) ([]*models.ReviewHandoff, error) {
+		return s.inner.MoveTeamMember(ctx, userID, teamName, choose)
+	})
+}
+
+func (s *instrumentedStore) RenameTeam(ctx context.Context, teamName, newTeamName string) error {
+	return s.exec(ctx, "RenameTeam", func(ctx context.Context) error {
+		return s.inner.RenameTeam(ctx, teamName, newTeamName)
+	})
+}
+
+func (s *instrumentedStore) DeleteTeam(ctx context.Context, teamName string, choose ReviewerChooser) ([]*models.ReviewHandoff, error) {
+	return instrument(s, ctx, "DeleteTeam", func(ctx context.Context) ([]*models.ReviewHandoff, error) {
+		return s.inner.DeleteTeam(ctx, teamName, choose)
+	})
+}
+
+func (s *instrumentedStore) SyncTeams(ctx context.Context, teams []models.Team, dryRun bool, choose ReviewerChooser) (*models.TeamSyncDiff, error) {
+	return instrument(s, ctx, "SyncTeams", func(ctx context.Context) (*models.TeamSyncDiff, error) {
+		return s.inner.SyncTeams(ctx, teams, dryRun, choose)
+	})
+}
+
+func (s *instrumentedStore) GetTeamStats(ctx context.Context) ([]*models.TeamStats, error) {
+	return instrument(s, ctx, "GetTeamStats", func(ctx context.Context) ([]*models.TeamStats, error) {
+		return s.inner.GetTeamStats(ctx)
+	})
+}
+
+func (s *instrumentedStore) UpdateUserActive(ctx context.Context, userID string, isActive bool, choose ReviewerChooser) (*models.User, []*models.ReviewHandoff, error) {
+	var handoffs []*models.ReviewHandoff
+	user, err := instrument(s, ctx, "UpdateUserActive", func(ctx context.Context) (user *models.User, err error) {
+		user, handoffs, err = s.inner.UpdateUserActive(ctx, userID, isActive, choose)
+		return user, err
+	})
+	return user, handoffs, err
+}
+
+func (s *instrumentedStore) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	return instrument(s, ctx, "GetUser", func(ctx context.Context) (*models.User, error) {
+		return s.inner.GetUser(ctx, userID)
+	})
+}
+
+func (s *instrumentedStore) UpdateUserSkills(ctx context.Context, userID string, skills []string) (*models.User, error) {
+	return instrument(s, ctx, "UpdateUserSkills", func(ctx context.Context) (*models.User, error) {
+		return s.inner.UpdateUserSkills(ctx, userID, skills)
+	})
+}
+
+func (s *instrumentedStore) UpdateUserSeniority(ctx context.Context, userID string, seniority string) (*models.User, error) {
+	return instrument(s, ctx, "UpdateUserSeniority", func(ctx context.Context) (*models.User, error) {
+		return s.inner.UpdateUserSeniority(ctx, userID, seniority)
+	})
+}
+
+func (s *instrumentedStore) UpdateUserLeave(ctx context.Context, userID string, leaveUntil *time.Time) (*models.User, error) {
+	return instrument(s, ctx, "UpdateUserLeave", func(ctx context.Context) (*models.User, error) {
+		return s.inner.UpdateUserLeave(ctx, userID, leaveUntil)
+	})
+}
+
+func (s *instrumentedStore) UpdateUserSchedule(ctx context.Context, userID string, schedule *models.WorkSchedule) (*models.User, error) {
+	return instrument(s, ctx, "UpdateUserSchedule", func(ctx context.Context) (*models.User, error) {
+		return s.inner.UpdateUserSchedule(ctx, userID, schedule)
+	})
+}
+
+func (s *instrumentedStore) GetActiveTeamMembers(ctx context.Context, teamName string, excludeUserID string) ([]*models.User, error) {
+	return instrument(s, ctx, "GetActiveTeamMembers", func(ctx context.Context) ([]*models.User, error) {
+		return s.inner.GetActiveTeamMembers(ctx, teamName, excludeUserID)
+	})
+}
+
+func (s *instrumentedStore) GetTeamUsers(ctx context.Context, teamName string) ([]*models.User, error) {
+	return instrument(s, ctx, "GetTeamUsers", func(ctx context.Context) ([]*models.User, error) {
+		return s.inner.GetTeamUsers(ctx, teamName)
+	})
+}
+
+func (s *instrumentedStore) CreatePR(ctx context.Context, pr *models.PullRequest) error {
+	return s.exec(ctx, "CreatePR", func(ctx context.Context) error {
+		return s.inner.CreatePR(ctx, pr)
+	})
+}
+
+func (s *instrumentedStore) GetPR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	return instrument(s, ctx, "GetPR", func(ctx context.Context) (*models.PullRequest, error) {
+		return s.inner.GetPR(ctx, prID)
+	})
+}
+
+func (s *instrumentedStore) MergePR(ctx context.Context, prID string) error {
+	return s.exec(ctx, "MergePR", func(ctx context.Context) error {
+		return s.inner.MergePR(ctx, prID)
+	})
+}
+
+func (s *instrumentedStore) ReassignPRReviewer(ctx context.Context, prID, oldUserID string, choose ReviewerChooser) (string, error) {
+	return instrument(s, ctx, "ReassignPRReviewer", func(ctx context.Context) (string, error) {
+		return s.inner.ReassignPRReviewer(ctx, prID, oldUserID, choose)
+	})
+}
+
+func (s *instrumentedStore) AddPRReviewer(ctx context.Context, prID, userID string) error {
+	return s.exec(ctx, "AddPRReviewer", func(ctx context.Context) error {
+		return s.inner.AddPRReviewer(ctx, prID, userID)
+	})
+}
+
+func (s *instrumentedStore) RemovePRReviewer(ctx context.Context, prID, userID string) error {
+	return s.exec(ctx, "RemovePRReviewer", func(ctx context.Context) error {
+		return s.inner.RemovePRReviewer(ctx, prID, userID)
+	})
+}
+
+func (s *instrumentedStore) GetPRHistory(ctx context.Context, prID string) ([]*models.ReviewerAssignment, error) {
+	return instrument(s, ctx, "GetPRHistory", func(ctx context.Context) ([]*models.ReviewerAssignment, error) {
+		return s.inner.GetPRHistory(ctx, prID)
+	})
+}
+
+func (s *instrumentedStore) RecordReviewDecision(ctx context.Context, prID, userID, decision string) error {
+	return s.exec(ctx, "RecordReviewDecision", func(ctx context.Context) error {
+		return s.inner.RecordReviewDecision(ctx, prID, userID, decision)
+	})
+}
+
+func (s *instrumentedStore) GetPendingReviews(ctx context.Context, teamName string) ([]*models.PendingReview, error) {
+	return instrument(s, ctx, "GetPendingReviews", func(ctx context.Context) ([]*models.PendingReview, error) {
+		return s.inner.GetPendingReviews(ctx, teamName)
+	})
+}
+
+func (s *instrumentedStore) GetStaleReviews(ctx context.Context) ([]*models.StaleReview, error) {
+	return instrument(s, ctx, "GetStaleReviews", func(ctx context.Context) ([]*models.StaleReview, error) {
+		return s.inner.GetStaleReviews(ctx)
+	})
+}
+
+func (s *instrumentedStore) MarkReviewEscalated(ctx context.Context, prID, userID string) error {
+	return s.exec(ctx, "MarkReviewEscalated", func(ctx context.Context) error {
+		return s.inner.MarkReviewEscalated(ctx, prID, userID)
+	})
+}
+
+func (s *instrumentedStore) AddBackupReviewer(ctx context.Context, prID, staleUserID string, choose ReviewerChooser) (string, error) {
+	return instrument(s, ctx, "AddBackupReviewer", func(ctx context.Context) (string, error) {
+		return s.inner.AddBackupReviewer(ctx, prID, staleUserID, choose)
+	})
+}
+
+func (s *instrumentedStore) ListPRs(ctx context.Context, filter models.PRListFilter) ([]*models.PullRequest, error) {
+	return instrument(s, ctx, "ListPRs", func(ctx context.Context) ([]*models.PullRequest, error) {
+		return s.inner.ListPRs(ctx, filter)
+	})
+}
+
+func (s *instrumentedStore) CreatePRs(ctx context.Context, prs []*models.PullRequest) ([]string, error) {
+	return instrument(s, ctx, "CreatePRs", func(ctx context.Context) ([]string, error) {
+		return s.inner.CreatePRs(ctx, prs)
+	})
+}
+
+func (s *instrumentedStore) ExistingPRIDs(ctx context.Context, prIDs []string) (map[string]bool, error) {
+	return instrument(s, ctx, "ExistingPRIDs", func(ctx context.Context) (map[string]bool, error) {
+		return s.inner.ExistingPRIDs(ctx, prIDs)
+	})
+}
+
+func (s *instrumentedStore) GetOpenReviewCounts(ctx context.Context, userIDs []string) (map[string]int, error) {
+	return instrument(s, ctx, "GetOpenReviewCounts", func(ctx context.Context) (map[string]int, error) {
+		return s.inner.GetOpenReviewCounts(ctx, userIDs)
+	})
+}
+
+func (s *instrumentedStore) CreateTeamRule(ctx context.Context, rule *models.TeamRule) error {
+	return s.exec(ctx, "CreateTeamRule", func(ctx context.Context) error {
+		return s.inner.CreateTeamRule(ctx, rule)
+	})
+}
+
+func (s *instrumentedStore) GetTeamRules(ctx context.Context, teamName string) ([]*models.TeamRule, error) {
+	return instrument(s, ctx, "GetTeamRules", func(ctx context.Context) ([]*models.TeamRule, error) {
+		return s.inner.GetTeamRules(ctx, teamName)
+	})
+}
+
+func (s *instrumentedStore) DeleteTeamRule(ctx context.Context, ruleID int64) error {
+	return s.exec(ctx, "DeleteTeamRule", func(ctx context.Context) error {
+		return s.inner.DeleteTeamRule(ctx, ruleID)
+	})
+}
+
+func (s *instrumentedStore) TryAdvisoryLock(ctx context.Context, key int64) (func() error, bool, error) {
+	var acquired bool
+	unlock, err := instrument(s, ctx, "TryAdvisoryLock", func(ctx context.Context) (unlock func() error, err error) {
+		unlock, acquired, err = s.inner.TryAdvisoryLock(ctx, key)
+		return unlock, err
+	})
+	return unlock, acquired, err
+}
+
+func (s *instrumentedStore) Close() error {
+	return s.inner.Close()
+}
@@ -1,30 +1,126 @@
 package store
 
-import "pr-reviewer/internal/models"
+import (
+	"context"
+	"database/sql"
+	"pr-reviewer/internal/models"
+	"time"
+)
 
 type TeamRepository interface {
-	CreateTeam(team *models.Team) error
-	GetTeam(teamName string) (*models.Team, error)
+	CreateTeam(ctx context.Context, team *models.Team) error
+	GetTeam(ctx context.Context, teamName string) (*models.Team, error)
+	UpdateTeamMaxReviewers(ctx context.Context, teamName string, maxReviewers int) error
+	UpdateTeamReviewSLA(ctx context.Context, teamName string, slaHours int) error
+	UpdateTeamEscalation(ctx context.Context, teamName string, staleAfterHours int, action string) error
+	AddTeamMembers(ctx context.Context, teamName string, members []models.TeamMember) error
+	RemoveTeamMember(ctx context.Context, teamName, userID string, choose ReviewerChooser) ([]*models.ReviewHandoff, error)
+	MoveTeamMember(ctx context.Context, userID, teamName string, choose ReviewerChooser) ([]*models.ReviewHandoff, error)
+	RenameTeam(ctx context.Context, teamName, newTeamName string) error
+	DeleteTeam(ctx context.Context, teamName string, choose ReviewerChooser) ([]*models.ReviewHandoff, error)
+	SyncTeams(ctx context.Context, teams []models.Team, dryRun bool, choose ReviewerChooser) (*models.TeamSyncDiff, error)
+	GetTeamStats(ctx context.Context) ([]*models.TeamStats, error)
 }
 
 type UserRepository interface {
-	UpdateUserActive(userID string, isActive bool) (*models.User, error)
-	GetUser(userID string) (*models.User, error)
-	GetActiveTeamMembers(teamName string, excludeUserID string) ([]*models.User, error)
+	UpdateUserActive(ctx context.Context, userID string, isActive bool, choose ReviewerChooser) (*models.User, []*models.ReviewHandoff, error)
+	GetUser(ctx context.Context, userID string) (*models.User, error)
+	UpdateUserSkills(ctx context.Context, userID string, skills []string) (*models.User, error)
+	UpdateUserSeniority(ctx context.Context, userID string, seniority string) (*models.User, error)
+	UpdateUserLeave(ctx context.Context, userID string, leaveUntil *time.Time) (*models.User, error)
+	UpdateUserSchedule(ctx context.Context, userID string, schedule *models.WorkSchedule) (*models.User, error)
+	GetActiveTeamMembers(ctx context.Context, teamName string, excludeUserID string) ([]*models.User, error)
+	GetTeamUsers(ctx context.Context, teamName string) ([]*models.User, error)
 }
 
+// ReviewerChoice is what a ReviewerChooser decides on. The store loads it in
+// the transaction that holds the PR lock, so the chooser needs no second
+// connection and sees the same snapshot as the swap.
+type ReviewerChoice struct {
+	PR      *models.PullRequest
+	OldUser *models.User
+	// Candidates are the active, unassigned members of the team the
+	// replacement comes from, and Rules that team's rules.
+	Candidates []*models.User
+	Rules      []*models.TeamRule
+	// Kept are the reviewers that stay on the PR.
+	Kept []*models.User
+	// OpenReviews counts the OPEN reviews of every candidate.
+	OpenReviews map[string]int
+}
+
+// ReviewerChooser picks the replacement for the old reviewer. It runs inside
+// the store transaction that holds the PR lock and must not call the store.
+// When handing off the reviews of a user who was deactivated or left their
+// team, an empty id means no replacement.
+type ReviewerChooser func(choice *ReviewerChoice) (string, error)
+
 type PRRepository interface {
-	CreatePR(pr *models.PullRequest) error
-	GetPR(prID string) (*models.PullRequest, error)
-	MergePR(prID string) error
-	UpdatePRReviewers(prID string, reviewers []string) error
-	GetUserReviewPRs(userID string) ([]*models.PullRequestShort, error)
-	IsUserAssignedToPR(prID, userID string) (bool, error)
+	CreatePR(ctx context.Context, pr *models.PullRequest) error
+	// CreatePRs inserts OPEN PRs with their initial reviewers in one
+	// transaction and returns the ids skipped because they already exist.
+	CreatePRs(ctx context.Context, prs []*models.PullRequest) ([]string, error)
+	ExistingPRIDs(ctx context.Context, prIDs []string) (map[string]bool, error)
+	GetPR(ctx context.Context, prID string) (*models.PullRequest, error)
+	MergePR(ctx context.Context, prID string) error
+	ReassignPRReviewer(ctx context.Context, prID, oldUserID string, choose ReviewerChooser) (string, error)
+	AddPRReviewer(ctx context.Context, prID, userID string) error
+	RemovePRReviewer(ctx context.Context, prID, userID string) error
+	GetPRHistory(ctx context.Context, prID string) ([]*models.ReviewerAssignment, error)
+	RecordReviewDecision(ctx context.Context, prID, userID, decision string) error
+	GetPendingReviews(ctx context.Context, teamName string) ([]*models.PendingReview, error)
+	GetStaleReviews(ctx context.Context) ([]*models.StaleReview, error)
+	MarkReviewEscalated(ctx context.Context, prID, userID string) error
+	AddBackupReviewer(ctx context.Context, prID, staleUserID string, choose ReviewerChooser) (string, error)
+	ListPRs(ctx context.Context, filter models.PRListFilter) ([]*models.PullRequest, error)
+	GetOpenReviewCounts(ctx context.Context, userIDs []string) (map[string]int, error)
+}
+
+type RuleRepository interface {
+	CreateTeamRule(ctx context.Context, rule *models.TeamRule) error
+	GetTeamRules(ctx context.Context, teamName string) ([]*models.TeamRule, error)
+	DeleteTeamRule(ctx context.Context, ruleID int64) error
+}
+
+// SchemaVersion is the migration the code expects. Every migration from 011
+// on records its number in schema_migrations.
+const SchemaVersion = 11
+
+// HealthChecker reports the state of the database for readiness probes.
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+	SchemaVersion(ctx context.Context) (int, error)
+	PoolStats() sql.DBStats
+}
+
+// Locker provides cross-replica mutual exclusion for background jobs.
+type Locker interface {
+	TryAdvisoryLock(ctx context.Context, key int64) (unlock func() error, acquired bool, err error)
+}
+
+// IsDatabaseError reports whether err is a failure of the database itself
+// rather than one of the store's error codes such as NOT_FOUND or PR_MERGED.
+func IsDatabaseError(err error) bool {
+	if err == nil {
+		return false
+	}
+	message := err.Error()
+	if message == "" {
+		return true
+	}
+	for _, c := range message {
+		if (c < 'A' || c > 'Z') && c != '_' {
+			return true
+		}
+	}
+	return false
 }
 
 type Store interface {
 	TeamRepository
 	UserRepository
 	PRRepository
+	RuleRepository
+	Locker
 	Close() error
 }
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/models"
+)
+
+// TestListPRsPagesThroughNullSortValues pages one PR at a time through PRs
+// whose created_at is partly NULL and expects every PR exactly once.
+func TestListPRsPagesThroughNullSortValues(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	team := &models.Team{TeamName: "backend", Members: []models.TeamMember{{UserID: "u1", Username: "u1", IsActive: true}}}
+	if err := s.CreateTeam(ctx, team); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 6; i++ {
+		pr := &models.PullRequest{PullRequestID: fmt.Sprintf("pr-%d", i), PullRequestName: "pr", AuthorID: "u1"}
+		if err := s.CreatePR(ctx, pr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE pull_requests SET created_at = NULL WHERE pull_request_id IN ('pr-1', 'pr-4')`); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, descending := range []bool{false, true} {
+		filter := models.PRListFilter{SortBy: "created_at", Descending: descending, Limit: 1}
+		seen := make(map[string]bool)
+		for page := 0; page < 10; page++ {
+			prs, err := s.ListPRs(ctx, filter)
+			if err != nil {
+				t.Fatalf("descending=%v page %d: %v", descending, page, err)
+			}
+			if len(prs) == 0 {
+				break
+			}
+			last := prs[0]
+			if seen[last.PullRequestID] {
+				t.Fatalf("descending=%v: %s listed twice", descending, last.PullRequestID)
+			}
+			seen[last.PullRequestID] = true
+
+			filter.AfterID = last.PullRequestID
+			filter.AfterNull = last.CreatedAt == nil
+			filter.AfterValue = ""
+			if last.CreatedAt != nil {
+				filter.AfterValue = last.CreatedAt.Format(time.RFC3339Nano)
+			}
+		}
+		if len(seen) != 6 {
+			t.Fatalf("descending=%v: listed %v, want all 6 PRs", descending, seen)
+		}
+	}
+}
+
+func TestListPRsCreatedRangeIgnoresOffset(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	team := &models.Team{TeamName: "backend", Members: []models.TeamMember{{UserID: "u1", Username: "u1", IsActive: true}}}
+	if err := s.CreateTeam(ctx, team); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreatePR(ctx, &models.PullRequest{PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.db.ExecContext(ctx, `UPDATE pull_requests SET created_at = '2030-05-01 10:00:00' WHERE pull_request_id = 'pr-1'`); err != nil {
+		t.Fatal(err)
+	}
+
+	// 12:30 at +03:00 is 09:30 UTC, before the PR was created.
+	from := time.Date(2030, 5, 1, 12, 30, 0, 0, time.FixedZone("MSK", 3*60*60))
+	prs, err := s.ListPRs(ctx, models.PRListFilter{SortBy: "created_at", CreatedFrom: &from, Limit: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(prs) != 1 {
+		t.Fatalf("listed %d PRs created from %v, want pr-1", len(prs), from)
+	}
+}
@@ -0,0 +1,89 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+var migrationName = regexp.MustCompile(`^(\d{3})_[a-z0-9_]+\.sql$`)
+
+// migrationFiles returns the migration files by number.
+func migrationFiles(t *testing.T) map[int]string {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join("..", "..", "migrations", "*.sql"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := make(map[int]string, len(paths))
+	for _, path := range paths {
+		match := migrationName.FindStringSubmatch(filepath.Base(path))
+		if match == nil {
+			t.Fatalf("%s does not match NNN_name.sql", filepath.Base(path))
+		}
+		number, _ := strconv.Atoi(match[1])
+		files[number] = path
+	}
+	return files
+}
+
+func TestMigrationsMatchSchemaVersion(t *testing.T) {
+	files := migrationFiles(t)
+	numbers := make([]int, 0, len(files))
+	for number := range files {
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+
+	for i, number := range numbers {
+		if number != i+1 {
+			t.Fatalf("migration numbers %v are not contiguous from 1", numbers)
+		}
+	}
+	if last := numbers[len(numbers)-1]; last != SchemaVersion {
+		t.Errorf("newest migration is %03d, SchemaVersion is %d", last, SchemaVersion)
+	}
+}
+
+// Every migration from 011 on must record itself, or /readyz keeps
+// reporting the schema as outdated after it is applied.
+func TestMigrationsRecordTheirVersion(t *testing.T) {
+	for number, path := range migrationFiles(t) {
+		if number < 11 {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		insert := regexp.MustCompile(`(?i)INSERT INTO schema_migrations \(version\) VALUES \(0*` + strconv.Itoa(number) + `\)`)
+		if !insert.Match(content) {
+			t.Errorf("%s does not insert version %d into schema_migrations", filepath.Base(path), number)
+		}
+	}
+}
+
+func TestSchemaVersionAfterMigrations(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	version, err := s.SchemaVersion(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != SchemaVersion {
+		t.Errorf("SchemaVersion = %d, want %d", version, SchemaVersion)
+	}
+
+	var recorded int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM schema_migrations").Scan(&recorded); err != nil {
+		t.Fatal(err)
+	}
+	if recorded != SchemaVersion {
+		t.Errorf("schema_migrations has %d rows, want one per migration up to %d", recorded, SchemaVersion)
+	}
+}
@@ -1,323 +1,2165 @@
 package store
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"log/slog"
 	"pr-reviewer/internal/models"
+	"sort"
+	"strings"
+	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type PostgresStore struct {
-	db *sql.DB
+	db *tracedDB
+	// queryTimeout bounds every store call, including its transaction, so
+	// a slow query cannot hold a connection indefinitely. Zero disables it.
+	queryTimeout time.Duration
 }
 
-func NewPostgresStore(connStr string) (*PostgresStore, error) {
+// Options configure the connection pool and startup of a PostgresStore.
+// Zero pool settings are not applied and keep the database/sql defaults
+// (unlimited open connections, two idle ones, no lifetime limits).
+type Options struct {
+	QueryTimeout    time.Duration
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	// ConnectTimeout is how long startup keeps retrying an unreachable
+	// database, with exponential backoff, before giving up. Zero pings once
+	// without retrying.
+	ConnectTimeout time.Duration
+}
+
+const (
+	initialConnectBackoff = 500 * time.Millisecond
+	maxConnectBackoff     = 10 * time.Second
+)
+
+func NewPostgresStore(ctx context.Context, connStr string, opts Options) (*PostgresStore, error) {
 	db, err := sql.Open("postgres", connStr)
 	if err != nil {
-		return nil, err
+		return nil, err
+	}
+
+	if opts.MaxOpenConns > 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns > 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime > 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	if opts.ConnMaxIdleTime > 0 {
+		db.SetConnMaxIdleTime(opts.ConnMaxIdleTime)
+	}
+
+	if err := waitForDatabase(ctx, db, opts.ConnectTimeout); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &PostgresStore{db: &tracedDB{DB: db}, queryTimeout: opts.QueryTimeout}, nil
+}
+
+// waitForDatabase pings db until it answers, backing off exponentially, so
+// the service can start before Postgres is accepting connections. A zero
+// timeout makes a single attempt.
+func waitForDatabase(ctx context.Context, db *sql.DB, timeout time.Duration) error {
+	if timeout <= 0 {
+		return db.PingContext(ctx)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := initialConnectBackoff
+	for attempt := 1; ; attempt++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return fmt.Errorf("database not reachable after %d attempts: %w", attempt, err)
+		}
+
+		slog.Warn("Database not reachable, retrying", "attempt", attempt, "retry_in", backoff.String(), "error", err)
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("database not reachable after %d attempts: %w", attempt, err)
+		case <-time.After(backoff):
+		}
+
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+}
+
+func (s *PostgresStore) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// SchemaVersion returns the newest applied migration, or 0 before
+// schema_migrations exists.
+func (s *PostgresStore) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	err := s.db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(version), 0) FROM schema_migrations
+	`).Scan(&version)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == "42P01" {
+			return 0, nil
+		}
+		return 0, err
+	}
+	return version, nil
+}
+
+func (s *PostgresStore) PoolStats() sql.DBStats {
+	return s.db.Stats()
+}
+
+// withTimeout derives the context of a single store call.
+func (s *PostgresStore) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if s.queryTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, s.queryTimeout)
+}
+
+func (s *PostgresStore) CreateTeam(ctx context.Context, team *models.Team) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM teams WHERE team_name = $1)", team.TeamName).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("TEAM_EXISTS")
+	}
+
+	if team.MaxReviewers <= 0 {
+		team.MaxReviewers = models.DefaultMaxReviewers
+	}
+	if team.StaleAction == "" {
+		team.StaleAction = models.StaleActionReassign
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO teams (team_name, max_reviewers, review_sla_hours, stale_after_hours, stale_action)
+		VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, 0), $5)
+	`, team.TeamName, team.MaxReviewers, team.ReviewSLAHours, team.StaleAfterHours, team.StaleAction)
+	if err != nil {
+		return err
+	}
+
+	for _, member := range team.Members {
+		if err := upsertTeamMember(ctx, tx, team.TeamName, member); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// AddTeamMembers adds members to an existing team. Members already in the
+// team are updated; users who belong to another team must be moved with
+// MoveTeamMember instead.
+func (s *PostgresStore) AddTeamMembers(ctx context.Context, teamName string, members []models.TeamMember) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockTeam(ctx, tx, teamName); err != nil {
+		return err
+	}
+
+	for _, member := range members {
+		if err := upsertTeamMember(ctx, tx, teamName, member); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// upsertTeamMember creates member in teamName or updates them if they are
+// already in it or have no team. It reports USER_IN_OTHER_TEAM rather than
+// silently moving a user between teams.
+func upsertTeamMember(ctx context.Context, q querier, teamName string, member models.TeamMember) error {
+	var currentTeam string
+	err := q.QueryRowContext(ctx, "SELECT COALESCE(team_name, '') FROM users WHERE user_id = $1 FOR UPDATE", member.UserID).Scan(&currentTeam)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	if currentTeam != "" && currentTeam != teamName {
+		return errors.New("USER_IN_OTHER_TEAM")
+	}
+
+	_, err = q.ExecContext(ctx, `
+		INSERT INTO users (user_id, username, team_name, is_active, skills, seniority) 
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (user_id) DO UPDATE SET 
+			username = EXCLUDED.username,
+			team_name = EXCLUDED.team_name,
+			is_active = EXCLUDED.is_active,
+			skills = EXCLUDED.skills,
+			seniority = EXCLUDED.seniority,
+			updated_at = NOW()
+	`, member.UserID, member.Username, teamName, member.IsActive, pq.Array(tagsOrEmpty(member.Skills)), member.Seniority)
+	return err
+}
+
+// lockTeam locks the team row until q's transaction ends so membership
+// changes of the same team are serialized.
+func lockTeam(ctx context.Context, q querier, teamName string) error {
+	var name string
+	err := q.QueryRowContext(ctx, "SELECT team_name FROM teams WHERE team_name = $1 FOR UPDATE", teamName).Scan(&name)
+	if err == sql.ErrNoRows {
+		return errors.New("NOT_FOUND")
+	}
+	return err
+}
+
+// lockUser locks and returns the user row until q's transaction ends.
+func lockUser(ctx context.Context, q querier, userID string) (*models.User, error) {
+	return scanUser(q.QueryRowContext(ctx, `
+		SELECT `+userColumns+` 
+		FROM users 
+		WHERE user_id = $1
+		FOR UPDATE
+	`, userID))
+}
+
+// RemoveTeamMember takes userID out of teamName. Their OPEN reviews are
+// handed off via handOffReviews, team rules naming them are deleted, and
+// they are left without a team and inactive. PRs they authored keep their
+// reviewers.
+func (s *PostgresStore) RemoveTeamMember(ctx context.Context, teamName, userID string, choose ReviewerChooser) ([]*models.ReviewHandoff, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockTeam(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+	user, err := lockUser(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TeamName != teamName {
+		return nil, errors.New("NOT_TEAM_MEMBER")
+	}
+
+	handoffs, err := handOffReviews(ctx, tx, user, choose, models.AssignReasonMembership)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := deleteMemberRules(ctx, tx, teamName, userID); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users 
+		SET team_name = NULL, is_active = false, updated_at = NOW() 
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return handoffs, nil
+}
+
+// MoveTeamMember moves userID into teamName. With a nil choose the user
+// keeps their OPEN reviews; otherwise the reviews are handed off first.
+// Rules of the old team naming the user are deleted, and PRs they authored
+// keep their reviewers. Moving a user into their current team is a no-op.
+func (s *PostgresStore) MoveTeamMember(ctx context.Context, userID, teamName string, choose ReviewerChooser) ([]*models.ReviewHandoff, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockTeam(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+	user, err := lockUser(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	handoffs := make([]*models.ReviewHandoff, 0)
+	if user.TeamName == teamName {
+		return handoffs, nil
+	}
+
+	if choose != nil {
+		if handoffs, err = handOffReviews(ctx, tx, user, choose, models.AssignReasonMembership); err != nil {
+			return nil, err
+		}
+	}
+
+	if user.TeamName != "" {
+		if err := deleteMemberRules(ctx, tx, user.TeamName, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users 
+		SET team_name = $1, updated_at = NOW() 
+		WHERE user_id = $2
+	`, teamName, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return handoffs, nil
+}
+
+// RenameTeam renames a team; members and rules follow through ON UPDATE
+// CASCADE and PRs are unaffected.
+func (s *PostgresStore) RenameTeam(ctx context.Context, teamName, newTeamName string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := lockTeam(ctx, tx, teamName); err != nil {
+		return err
+	}
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM teams WHERE team_name = $1)", newTeamName).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return errors.New("TEAM_EXISTS")
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE teams SET team_name = $1 WHERE team_name = $2", newTeamName, teamName); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// DeleteTeam deletes a team and its rules. It reports TEAM_HAS_OPEN_PRS
+// while any member authors an OPEN PR. Members' OPEN reviews on other
+// teams' PRs are handed off, and the members are left without a team and
+// inactive.
+func (s *PostgresStore) DeleteTeam(ctx context.Context, teamName string, choose ReviewerChooser) ([]*models.ReviewHandoff, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if err := lockTeam(ctx, tx, teamName); err != nil {
+		return nil, err
+	}
+
+	var hasOpenPRs bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT EXISTS(
+			SELECT 1 FROM pull_requests pr
+			JOIN users a ON a.user_id = pr.author_id
+			WHERE a.team_name = $1 AND pr.status = 'OPEN'
+		)
+	`, teamName).Scan(&hasOpenPRs)
+	if err != nil {
+		return nil, err
+	}
+	if hasOpenPRs {
+		return nil, errors.New("TEAM_HAS_OPEN_PRS")
+	}
+
+	members, err := queryUsers(ctx, tx, `
+		SELECT `+userColumns+` 
+		FROM users 
+		WHERE team_name = $1
+		ORDER BY user_id
+		FOR UPDATE
+	`, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	handoffs := make([]*models.ReviewHandoff, 0)
+	for _, member := range members {
+		memberHandoffs, err := handOffReviews(ctx, tx, member, choose, models.AssignReasonMembership)
+		if err != nil {
+			return nil, err
+		}
+		handoffs = append(handoffs, memberHandoffs...)
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE users 
+		SET team_name = NULL, is_active = false, updated_at = NOW() 
+		WHERE team_name = $1
+	`, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM teams WHERE team_name = $1", teamName); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return handoffs, nil
+}
+
+// SyncTeams makes teams the complete set of teams and their members in one
+// transaction. Missing teams are created with the given settings, teams not
+// listed are deleted, users not listed in any team are removed as in
+// RemoveTeamMember, and listed users are added, moved or updated. Removed and
+// moved members hand off their OPEN reviews after the membership changes, so
+// replacements come from the new membership. A sync that would delete a team
+// whose members author OPEN PRs fails with TEAM_HAS_OPEN_PRS; with dryRun
+// such teams are listed as blocked instead. With dryRun the changes are
+// rolled back and only the diff is returned. Settings of existing teams are
+// left unchanged.
+func (s *PostgresStore) SyncTeams(ctx context.Context, teams []models.Team, dryRun bool, choose ReviewerChooser) (*models.TeamSyncDiff, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT team_name FROM teams ORDER BY team_name FOR UPDATE")
+	if err != nil {
+		return nil, err
+	}
+	existingTeams := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		existingTeams[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	users, err := queryUsers(ctx, tx, `
+		SELECT `+userColumns+` 
+		FROM users 
+		ORDER BY user_id
+		FOR UPDATE
+	`)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]*models.User, len(users))
+	for _, user := range users {
+		current[user.UserID] = user
+	}
+
+	wantedTeams := make(map[string]bool, len(teams))
+	for _, team := range teams {
+		wantedTeams[team.TeamName] = true
+	}
+	var unlisted []string
+	for name := range existingTeams {
+		if !wantedTeams[name] {
+			unlisted = append(unlisted, name)
+		}
+	}
+
+	// Teams are deleted only without OPEN PRs by their members, as in
+	// DeleteTeam. Authorship is checked before any member is moved away.
+	rows, err = tx.QueryContext(ctx, `
+		SELECT DISTINCT a.team_name
+		FROM pull_requests pr
+		JOIN users a ON a.user_id = pr.author_id
+		WHERE a.team_name = ANY($1) AND pr.status = 'OPEN'
+		ORDER BY a.team_name
+	`, pq.Array(unlisted))
+	if err != nil {
+		return nil, err
+	}
+	blockedTeams := make([]string, 0)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		blockedTeams = append(blockedTeams, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(blockedTeams) > 0 && !dryRun {
+		return nil, errors.New("TEAM_HAS_OPEN_PRS")
+	}
+
+	diff := &models.TeamSyncDiff{
+		CreatedTeams:      make([]string, 0),
+		DeletedTeams:      make([]string, 0),
+		BlockedTeams:      blockedTeams,
+		Added:             make([]models.MembershipChange, 0),
+		Removed:           make([]models.MembershipChange, 0),
+		Moved:             make([]models.MembershipChange, 0),
+		ActivationChanges: make([]models.ActivationChange, 0),
+		Updated:           make([]string, 0),
+		Handoffs:          make([]*models.ReviewHandoff, 0),
+	}
+
+	listed := make(map[string]bool)
+	var leaving []*models.User
+	for _, team := range teams {
+		if !existingTeams[team.TeamName] {
+			diff.CreatedTeams = append(diff.CreatedTeams, team.TeamName)
+			maxReviewers, staleAction := team.MaxReviewers, team.StaleAction
+			if maxReviewers <= 0 {
+				maxReviewers = models.DefaultMaxReviewers
+			}
+			if staleAction == "" {
+				staleAction = models.StaleActionReassign
+			}
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO teams (team_name, max_reviewers, review_sla_hours, stale_after_hours, stale_action)
+				VALUES ($1, $2, NULLIF($3, 0), NULLIF($4, 0), $5)
+			`, team.TeamName, maxReviewers, team.ReviewSLAHours, team.StaleAfterHours, staleAction)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, member := range team.Members {
+			listed[member.UserID] = true
+			user := current[member.UserID]
+			switch {
+			case user == nil || user.TeamName == "":
+				diff.Added = append(diff.Added, models.MembershipChange{UserID: member.UserID, ToTeam: team.TeamName})
+			case user.TeamName != team.TeamName:
+				diff.Moved = append(diff.Moved, models.MembershipChange{UserID: member.UserID, FromTeam: user.TeamName, ToTeam: team.TeamName})
+				leaving = append(leaving, user)
+				if err := deleteMemberRules(ctx, tx, user.TeamName, user.UserID); err != nil {
+					return nil, err
+				}
+			}
+			if user != nil {
+				if user.IsActive != member.IsActive {
+					diff.ActivationChanges = append(diff.ActivationChanges, models.ActivationChange{UserID: member.UserID, IsActive: member.IsActive})
+				}
+				if user.Username != member.Username || user.Seniority != member.Seniority || !sameTags(user.Skills, member.Skills) {
+					diff.Updated = append(diff.Updated, member.UserID)
+				}
+			}
+
+			_, err = tx.ExecContext(ctx, `
+				INSERT INTO users (user_id, username, team_name, is_active, skills, seniority) 
+				VALUES ($1, $2, $3, $4, $5, $6)
+				ON CONFLICT (user_id) DO UPDATE SET 
+					username = EXCLUDED.username,
+					team_name = EXCLUDED.team_name,
+					is_active = EXCLUDED.is_active,
+					skills = EXCLUDED.skills,
+					seniority = EXCLUDED.seniority,
+					updated_at = NOW()
+			`, member.UserID, member.Username, team.TeamName, member.IsActive, pq.Array(tagsOrEmpty(member.Skills)), member.Seniority)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, user := range users {
+		if user.TeamName == "" || listed[user.UserID] {
+			continue
+		}
+		diff.Removed = append(diff.Removed, models.MembershipChange{UserID: user.UserID, FromTeam: user.TeamName})
+		leaving = append(leaving, user)
+		if err := deleteMemberRules(ctx, tx, user.TeamName, user.UserID); err != nil {
+			return nil, err
+		}
+		_, err = tx.ExecContext(ctx, `
+			UPDATE users 
+			SET team_name = NULL, is_active = false, updated_at = NOW() 
+			WHERE user_id = $1
+		`, user.UserID)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	blocked := make(map[string]bool, len(blockedTeams))
+	for _, name := range blockedTeams {
+		blocked[name] = true
+	}
+	for _, name := range unlisted {
+		if !blocked[name] {
+			diff.DeletedTeams = append(diff.DeletedTeams, name)
+		}
+	}
+	sort.Strings(diff.DeletedTeams)
+	for _, name := range diff.DeletedTeams {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM teams WHERE team_name = $1", name); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, user := range leaving {
+		handoffs, err := handOffReviews(ctx, tx, user, choose, models.AssignReasonMembership)
+		if err != nil {
+			return nil, err
+		}
+		diff.Handoffs = append(diff.Handoffs, handoffs...)
+	}
+
+	if dryRun {
+		return diff, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return diff, nil
+}
+
+func sameTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// handOffReviews unassigns user from every OPEN PR they review. Each PR is
+// locked in turn and choose picks a replacement among the active members of
+// the author's team who are not yet assigned; an empty choice leaves the
+// review without a replacement. The history records the change with reason.
+func handOffReviews(ctx context.Context, tx *tracedTx, user *models.User, choose ReviewerChooser, reason string) ([]*models.ReviewHandoff, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT prr.pull_request_id
+		FROM pull_request_reviewers prr
+		JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id
+		WHERE prr.user_id = $1 AND pr.status = 'OPEN'
+		ORDER BY prr.pull_request_id
+	`, user.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	var prIDs []string
+	for rows.Next() {
+		var prID string
+		if err := rows.Scan(&prID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		prIDs = append(prIDs, prID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	handoffs := make([]*models.ReviewHandoff, 0, len(prIDs))
+	for _, prID := range prIDs {
+		pr, err := getPR(ctx, tx, prID, true)
+		if err != nil {
+			return nil, err
+		}
+
+		var authorTeam string
+		err = tx.QueryRowContext(ctx, "SELECT COALESCE(team_name, '') FROM users WHERE user_id = $1", pr.AuthorID).Scan(&authorTeam)
+		if err != nil {
+			return nil, err
+		}
+
+		choice, err := loadReviewerChoice(ctx, tx, pr, user, authorTeam)
+		if err != nil {
+			return nil, err
+		}
+
+		newUserID, err := choose(choice)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := unassignReviewer(ctx, tx, prID, user.UserID, reason); err != nil {
+			return nil, err
+		}
+		if newUserID != "" {
+			if err := assignReviewer(ctx, tx, prID, newUserID, reason); err != nil {
+				return nil, err
+			}
+		}
+
+		handoffs = append(handoffs, &models.ReviewHandoff{
+			PullRequestID: prID,
+			OldReviewerID: user.UserID,
+			NewReviewerID: newUserID,
+		})
+	}
+
+	return handoffs, nil
+}
+
+func deleteMemberRules(ctx context.Context, q querier, teamName, userID string) error {
+	_, err := q.ExecContext(ctx, `
+		DELETE FROM team_rules 
+		WHERE team_name = $1 AND (author_id = $2 OR reviewer_id = $2)
+	`, teamName, userID)
+	return err
+}
+
+func (s *PostgresStore) GetTeam(ctx context.Context, teamName string) (*models.Team, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var team models.Team
+	team.TeamName = teamName
+
+	err := s.db.QueryRowContext(ctx, `
+		SELECT max_reviewers, COALESCE(review_sla_hours, 0), COALESCE(stale_after_hours, 0), stale_action
+		FROM teams
+		WHERE team_name = $1
+	`, teamName).Scan(&team.MaxReviewers, &team.ReviewSLAHours, &team.StaleAfterHours, &team.StaleAction)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("NOT_FOUND")
+		}
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id, username, is_active, skills, seniority 
+		FROM users 
+		WHERE team_name = $1
+		ORDER BY user_id
+	`, teamName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var member models.TeamMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.IsActive, pq.Array(&member.Skills), &member.Seniority); err != nil {
+			return nil, err
+		}
+		team.Members = append(team.Members, member)
+	}
+
+	if team.Members == nil {
+		team.Members = make([]models.TeamMember, 0)
+	}
+
+	return &team, rows.Err()
+}
+
+// userColumns lists the users columns in the order scanUser expects them.
+// team_name is NULL for users who were removed from their team.
+const userColumns = "user_id, username, COALESCE(team_name, ''), is_active, skills, seniority, leave_until, work_schedule"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// querier is implemented by both *tracedDB and *tracedTx so read helpers can
+// be shared between plain queries and transactions.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*tracedRows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func scanUser(row rowScanner) (*models.User, error) {
+	var user models.User
+	var leaveUntil sql.NullTime
+	var schedule []byte
+	err := row.Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive, pq.Array(&user.Skills), &user.Seniority, &leaveUntil, &schedule)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("NOT_FOUND")
+		}
+		return nil, err
+	}
+
+	if leaveUntil.Valid {
+		user.LeaveUntil = &leaveUntil.Time
+	}
+	if user.WorkSchedule, err = decodeSchedule(schedule); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+func decodeSchedule(raw []byte) (*models.WorkSchedule, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var schedule models.WorkSchedule
+	if err := json.Unmarshal(raw, &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func encodeSchedule(schedule *models.WorkSchedule) (interface{}, error) {
+	if schedule == nil {
+		return nil, nil
+	}
+	raw, err := json.Marshal(schedule)
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// UpdateUserActive sets the user's active flag. A user who is deactivated
+// hands off their OPEN reviews via handOffReviews in the same transaction,
+// unless choose is nil.
+func (s *PostgresStore) UpdateUserActive(ctx context.Context, userID string, isActive bool, choose ReviewerChooser) (*models.User, []*models.ReviewHandoff, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback()
+
+	user, err := scanUser(tx.QueryRowContext(ctx, `
+		UPDATE users 
+		SET is_active = $1, updated_at = NOW() 
+		WHERE user_id = $2
+		RETURNING `+userColumns, isActive, userID))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	handoffs := make([]*models.ReviewHandoff, 0)
+	if !isActive && choose != nil {
+		if handoffs, err = handOffReviews(ctx, tx, user, choose, models.AssignReasonDeactivation); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return user, handoffs, nil
+}
+
+func (s *PostgresStore) GetUser(ctx context.Context, userID string) (*models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return scanUser(s.db.QueryRowContext(ctx, `
+		SELECT `+userColumns+` 
+		FROM users 
+		WHERE user_id = $1
+	`, userID))
+}
+
+func (s *PostgresStore) UpdateUserSkills(ctx context.Context, userID string, skills []string) (*models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return scanUser(s.db.QueryRowContext(ctx, `
+		UPDATE users 
+		SET skills = $1, updated_at = NOW() 
+		WHERE user_id = $2
+		RETURNING `+userColumns, pq.Array(tagsOrEmpty(skills)), userID))
+}
+
+func (s *PostgresStore) UpdateUserSeniority(ctx context.Context, userID string, seniority string) (*models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return scanUser(s.db.QueryRowContext(ctx, `
+		UPDATE users 
+		SET seniority = $1, updated_at = NOW() 
+		WHERE user_id = $2
+		RETURNING `+userColumns, seniority, userID))
+}
+
+func (s *PostgresStore) UpdateUserLeave(ctx context.Context, userID string, leaveUntil *time.Time) (*models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	// Timestamps are stored as UTC in TIMESTAMP columns. lib/pq sends the
+	// time with its offset, which Postgres drops, so convert first.
+	if leaveUntil != nil {
+		utc := leaveUntil.UTC()
+		leaveUntil = &utc
+	}
+	return scanUser(s.db.QueryRowContext(ctx, `
+		UPDATE users 
+		SET leave_until = $1, updated_at = NOW() 
+		WHERE user_id = $2
+		RETURNING `+userColumns, leaveUntil, userID))
+}
+
+func (s *PostgresStore) UpdateUserSchedule(ctx context.Context, userID string, schedule *models.WorkSchedule) (*models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	raw, err := encodeSchedule(schedule)
+	if err != nil {
+		return nil, err
+	}
+	return scanUser(s.db.QueryRowContext(ctx, `
+		UPDATE users 
+		SET work_schedule = $1, updated_at = NOW() 
+		WHERE user_id = $2
+		RETURNING `+userColumns, raw, userID))
+}
+
+// GetActiveTeamMembers returns members that can currently review: active and
+// not on leave.
+func (s *PostgresStore) GetActiveTeamMembers(ctx context.Context, teamName string, excludeUserID string) ([]*models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return getActiveTeamMembers(ctx, s.db, teamName, excludeUserID)
+}
+
+func getActiveTeamMembers(ctx context.Context, q querier, teamName string, excludeUserID string) ([]*models.User, error) {
+	return queryUsers(ctx, q, `
+		SELECT `+userColumns+` 
+		FROM users 
+		WHERE team_name = $1 AND is_active = true AND user_id != $2
+			AND (leave_until IS NULL OR leave_until <= NOW())
+		ORDER BY user_id
+	`, teamName, excludeUserID)
+}
+
+func (s *PostgresStore) GetTeamUsers(ctx context.Context, teamName string) ([]*models.User, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return queryUsers(ctx, s.db, `
+		SELECT `+userColumns+` 
+		FROM users 
+		WHERE team_name = $1
+		ORDER BY user_id
+	`, teamName)
+}
+
+// loadReviewerChoice loads, through q, the candidates from teamName and the
+// data a ReviewerChooser needs to replace oldUser on pr.
+func loadReviewerChoice(ctx context.Context, q querier, pr *models.PullRequest, oldUser *models.User, teamName string) (*ReviewerChoice, error) {
+	members, err := getActiveTeamMembers(ctx, q, teamName, pr.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+
+	assigned := make(map[string]bool, len(pr.AssignedReviewers))
+	keptIDs := make([]string, 0, len(pr.AssignedReviewers))
+	for _, reviewerID := range pr.AssignedReviewers {
+		assigned[reviewerID] = true
+		if reviewerID != oldUser.UserID {
+			keptIDs = append(keptIDs, reviewerID)
+		}
+	}
+
+	choice := &ReviewerChoice{PR: pr, OldUser: oldUser, Candidates: make([]*models.User, 0, len(members))}
+	candidateIDs := make([]string, 0, len(members))
+	for _, member := range members {
+		if !assigned[member.UserID] {
+			choice.Candidates = append(choice.Candidates, member)
+			candidateIDs = append(candidateIDs, member.UserID)
+		}
+	}
+
+	choice.Kept, err = queryUsers(ctx, q, `
+		SELECT `+userColumns+`
+		FROM users
+		WHERE user_id = ANY($1)
+		ORDER BY user_id
+	`, pq.Array(keptIDs))
+	if err != nil {
+		return nil, err
+	}
+
+	choice.Rules, err = getTeamRules(ctx, q, teamName)
+	if err != nil {
+		return nil, err
+	}
+
+	choice.OpenReviews, err = getOpenReviewCounts(ctx, q, candidateIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	return choice, nil
+}
+
+func queryUsers(ctx context.Context, q querier, query string, args ...interface{}) ([]*models.User, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*models.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+func (s *PostgresStore) CreatePR(ctx context.Context, pr *models.PullRequest) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id = $1)", pr.PullRequestID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("PR_EXISTS")
+	}
+
+	var authorExists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)", pr.AuthorID).Scan(&authorExists)
+	if err != nil {
+		return err
+	}
+	if !authorExists {
+		return errors.New("NOT_FOUND")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, labels)
+		VALUES ($1, $2, $3, 'OPEN', $4)
+	`, pr.PullRequestID, pr.PullRequestName, pr.AuthorID, pq.Array(tagsOrEmpty(pr.Labels)))
+	if err != nil {
+		return err
+	}
+
+	for _, reviewerID := range pr.AssignedReviewers {
+		if err := assignReviewer(ctx, tx, pr.PullRequestID, reviewerID, models.AssignReasonInitial); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// CreatePRs inserts all PRs with one statement per table, passing the
+// columns as arrays. Labels travel as array literals and are cast back, as
+// unnest cannot produce one text[] per row. PRs that already exist, possibly
+// created concurrently, are skipped along with their reviewers.
+func (s *PostgresStore) CreatePRs(ctx context.Context, prs []*models.PullRequest) ([]string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	if len(prs) == 0 {
+		return []string{}, nil
+	}
+
+	ids := make([]string, len(prs))
+	names := make([]string, len(prs))
+	authors := make([]string, len(prs))
+	labels := make([]string, len(prs))
+	for i, pr := range prs {
+		literal, err := pq.StringArray(tagsOrEmpty(pr.Labels)).Value()
+		if err != nil {
+			return nil, err
+		}
+		ids[i], names[i], authors[i], labels[i] = pr.PullRequestID, pr.PullRequestName, pr.AuthorID, literal.(string)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, labels)
+		SELECT id, name, author_id, 'OPEN', labels::text[]
+		FROM unnest($1::text[], $2::text[], $3::text[], $4::text[]) AS t(id, name, author_id, labels)
+		ON CONFLICT (pull_request_id) DO NOTHING
+		RETURNING pull_request_id
+	`, pq.Array(ids), pq.Array(names), pq.Array(authors), pq.Array(labels))
+	if err != nil {
+		return nil, err
+	}
+	inserted := make(map[string]bool, len(prs))
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		inserted[id] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	skipped := make([]string, 0)
+	var reviewerPRs, reviewerIDs []string
+	for _, pr := range prs {
+		if !inserted[pr.PullRequestID] {
+			skipped = append(skipped, pr.PullRequestID)
+			continue
+		}
+		for _, reviewerID := range pr.AssignedReviewers {
+			reviewerPRs = append(reviewerPRs, pr.PullRequestID)
+			reviewerIDs = append(reviewerIDs, reviewerID)
+		}
+	}
+
+	if len(reviewerIDs) > 0 {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO pull_request_reviewers (pull_request_id, user_id)
+			SELECT * FROM unnest($1::text[], $2::text[])
+		`, pq.Array(reviewerPRs), pq.Array(reviewerIDs))
+		if err != nil {
+			return nil, err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO reviewer_assignments (pull_request_id, user_id, reason)
+			SELECT pr_id, user_id, $3 FROM unnest($1::text[], $2::text[]) AS t(pr_id, user_id)
+		`, pq.Array(reviewerPRs), pq.Array(reviewerIDs), models.AssignReasonInitial)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return skipped, tx.Commit()
+}
+
+func (s *PostgresStore) ExistingPRIDs(ctx context.Context, prIDs []string) (map[string]bool, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	existing := make(map[string]bool)
+	if len(prIDs) == 0 {
+		return existing, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT pull_request_id FROM pull_requests WHERE pull_request_id = ANY($1)
+	`, pq.Array(prIDs))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		existing[id] = true
+	}
+
+	return existing, rows.Err()
+}
+
+func (s *PostgresStore) GetPR(ctx context.Context, prID string) (*models.PullRequest, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return getPR(ctx, s.db, prID, false)
+}
+
+// getPR loads a PR with its reviewers. With forUpdate the PR row stays
+// locked until q's transaction ends.
+func getPR(ctx context.Context, q querier, prID string, forUpdate bool) (*models.PullRequest, error) {
+	var pr models.PullRequest
+	var createdAt, mergedAt sql.NullTime
+
+	lock := ""
+	if forUpdate {
+		lock = "FOR UPDATE"
+	}
+
+	err := q.QueryRowContext(ctx, `
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status, pr.labels, pr.created_at, pr.merged_at
+		FROM pull_requests pr
+		WHERE pr.pull_request_id = $1
+	`+lock, prID).Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, pq.Array(&pr.Labels), &createdAt, &mergedAt)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.New("NOT_FOUND")
+		}
+		return nil, err
+	}
+
+	if createdAt.Valid {
+		pr.CreatedAt = &createdAt.Time
+	}
+	if mergedAt.Valid {
+		pr.MergedAt = &mergedAt.Time
+	}
+
+	rows, err := q.QueryContext(ctx, `
+		SELECT user_id 
+		FROM pull_request_reviewers 
+		WHERE pull_request_id = $1
+		ORDER BY assigned_at
+	`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reviewerID string
+		if err := rows.Scan(&reviewerID); err != nil {
+			return nil, err
+		}
+		pr.AssignedReviewers = append(pr.AssignedReviewers, reviewerID)
+	}
+
+	return &pr, rows.Err()
+}
+
+func (s *PostgresStore) MergePR(ctx context.Context, prID string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE pull_requests 
+		SET status = 'MERGED', merged_at = NOW() 
+		WHERE pull_request_id = $1 AND status != 'MERGED'
+	`, prID)
+	return err
+}
+
+// assignReviewer adds userID to the current reviewers and opens a history
+// entry for the assignment.
+func assignReviewer(ctx context.Context, q querier, prID, userID, reason string) error {
+	_, err := q.ExecContext(ctx, `
+		INSERT INTO pull_request_reviewers (pull_request_id, user_id)
+		VALUES ($1, $2)
+	`, prID, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = q.ExecContext(ctx, `
+		INSERT INTO reviewer_assignments (pull_request_id, user_id, reason)
+		VALUES ($1, $2, $3)
+	`, prID, userID, reason)
+	return err
+}
+
+// unassignReviewer removes userID from the current reviewers and closes its
+// open history entry. It reports NOT_ASSIGNED when userID was not assigned.
+func unassignReviewer(ctx context.Context, q querier, prID, userID, reason string) error {
+	result, err := q.ExecContext(ctx, `
+		DELETE FROM pull_request_reviewers
+		WHERE pull_request_id = $1 AND user_id = $2
+	`, prID, userID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("NOT_ASSIGNED")
+	}
+
+	_, err = q.ExecContext(ctx, `
+		UPDATE reviewer_assignments
+		SET unassigned_at = NOW(), unassign_reason = $3
+		WHERE pull_request_id = $1 AND user_id = $2 AND unassigned_at IS NULL
+	`, prID, userID, reason)
+	return err
+}
+
+func (s *PostgresStore) GetPRHistory(ctx context.Context, prID string) ([]*models.ReviewerAssignment, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	var exists bool
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id = $1)", prID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("NOT_FOUND")
+	}
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT user_id, reason, assigned_at, COALESCE(unassign_reason, ''), unassigned_at,
+			COALESCE(decision, ''), first_decision_at
+		FROM reviewer_assignments
+		WHERE pull_request_id = $1
+		ORDER BY assigned_at, id
+	`, prID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := make([]*models.ReviewerAssignment, 0)
+	for rows.Next() {
+		var entry models.ReviewerAssignment
+		var unassignedAt, decidedAt sql.NullTime
+		if err := rows.Scan(&entry.UserID, &entry.Reason, &entry.AssignedAt, &entry.UnassignReason, &unassignedAt,
+			&entry.Decision, &decidedAt); err != nil {
+			return nil, err
+		}
+		if unassignedAt.Valid {
+			entry.UnassignedAt = &unassignedAt.Time
+		}
+		if decidedAt.Valid {
+			entry.FirstDecisionAt = &decidedAt.Time
+		}
+		history = append(history, &entry)
+	}
+
+	return history, rows.Err()
+}
+
+// ReassignPRReviewer replaces oldUserID on an OPEN PR in one transaction.
+// The PR row is locked first, so concurrent reassigns, manual reviewer
+// changes and merges on the same PR are serialized. choose receives the
+// locked PR, the old reviewer and the active members of the old reviewer's
+// team who are neither the author nor already assigned.
+func (s *PostgresStore) ReassignPRReviewer(ctx context.Context, prID, oldUserID string, choose ReviewerChooser) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	pr, err := getPR(ctx, tx, prID, true)
+	if err != nil {
+		return "", err
+	}
+
+	if pr.Status == "MERGED" {
+		return "", errors.New("PR_MERGED")
+	}
+
+	assigned := make(map[string]bool, len(pr.AssignedReviewers))
+	for _, reviewerID := range pr.AssignedReviewers {
+		assigned[reviewerID] = true
+	}
+	if !assigned[oldUserID] {
+		return "", errors.New("NOT_ASSIGNED")
+	}
+
+	oldUser, err := scanUser(tx.QueryRowContext(ctx, `
+		SELECT `+userColumns+` 
+		FROM users 
+		WHERE user_id = $1
+	`, oldUserID))
+	if err != nil {
+		return "", err
+	}
+
+	choice, err := loadReviewerChoice(ctx, tx, pr, oldUser, oldUser.TeamName)
+	if err != nil {
+		return "", err
+	}
+
+	newUserID, err := choose(choice)
+	if err != nil {
+		return "", err
+	}
+
+	if err := unassignReviewer(ctx, tx, prID, oldUserID, models.AssignReasonReassign); err != nil {
+		return "", err
+	}
+	if err := assignReviewer(ctx, tx, prID, newUserID, models.AssignReasonReassign); err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return newUserID, nil
+}
+
+// GetTeamStats counts OPEN PRs by the team's authors and active members for
+// every team.
+func (s *PostgresStore) GetTeamStats(ctx context.Context) ([]*models.TeamStats, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT t.team_name,
+			(SELECT COUNT(*) FROM pull_requests pr JOIN users a ON a.user_id = pr.author_id
+				WHERE a.team_name = t.team_name AND pr.status = 'OPEN'),
+			(SELECT COUNT(*) FROM users u WHERE u.team_name = t.team_name AND u.is_active = true)
+		FROM teams t
+		ORDER BY t.team_name
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := make([]*models.TeamStats, 0)
+	for rows.Next() {
+		var team models.TeamStats
+		if err := rows.Scan(&team.TeamName, &team.OpenPRs, &team.ActiveUsers); err != nil {
+			return nil, err
+		}
+		stats = append(stats, &team)
+	}
+
+	return stats, rows.Err()
+}
+
+func (s *PostgresStore) UpdateTeamMaxReviewers(ctx context.Context, teamName string, maxReviewers int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, "UPDATE teams SET max_reviewers = $1 WHERE team_name = $2", maxReviewers, teamName)
+	if err != nil {
+		return err
 	}
 
-	if err := db.Ping(); err != nil {
-		return nil, err
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("NOT_FOUND")
 	}
 
-	return &PostgresStore{db: db}, nil
+	return nil
 }
 
-func (s *PostgresStore) CreateTeam(team *models.Team) error {
-	tx, err := s.db.Begin()
+func (s *PostgresStore) UpdateTeamReviewSLA(ctx context.Context, teamName string, slaHours int) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE teams SET review_sla_hours = NULLIF($1, 0) WHERE team_name = $2
+	`, slaHours, teamName)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
-	var exists bool
-	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM teams WHERE team_name = $1)", team.TeamName).Scan(&exists)
+	affected, err := result.RowsAffected()
 	if err != nil {
 		return err
 	}
-	if exists {
-		return fmt.Errorf("TEAM_EXISTS")
+	if affected == 0 {
+		return errors.New("NOT_FOUND")
 	}
 
-	_, err = tx.Exec("INSERT INTO teams (team_name) VALUES ($1)", team.TeamName)
+	return nil
+}
+
+func (s *PostgresStore) UpdateTeamEscalation(ctx context.Context, teamName string, staleAfterHours int, action string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, `
+		UPDATE teams SET stale_after_hours = NULLIF($1, 0), stale_action = $2 WHERE team_name = $3
+	`, staleAfterHours, action, teamName)
 	if err != nil {
 		return err
 	}
 
-	for _, member := range team.Members {
-		_, err = tx.Exec(`
-			INSERT INTO users (user_id, username, team_name, is_active) 
-			VALUES ($1, $2, $3, $4)
-			ON CONFLICT (user_id) DO UPDATE SET 
-				username = EXCLUDED.username,
-				team_name = EXCLUDED.team_name,
-				is_active = EXCLUDED.is_active,
-				updated_at = NOW()
-		`, member.UserID, member.Username, team.TeamName, member.IsActive)
-		if err != nil {
-			return err
-		}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("NOT_FOUND")
 	}
 
-	return tx.Commit()
+	return nil
 }
 
-func (s *PostgresStore) GetTeam(teamName string) (*models.Team, error) {
-	var team models.Team
-	team.TeamName = teamName
+// GetStaleReviews lists undecided, not yet escalated reviewers of OPEN PRs
+// whose team has a staleness threshold. Callers compare AssignedAt against
+// the threshold themselves.
+func (s *PostgresStore) GetStaleReviews(ctx context.Context) ([]*models.StaleReview, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 
-	rows, err := s.db.Query(`
-		SELECT user_id, username, is_active 
-		FROM users 
-		WHERE team_name = $1
-		ORDER BY user_id
-	`, teamName)
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT pr.pull_request_id, pr.author_id, t.team_name, prr.user_id, prr.assigned_at,
+			t.stale_after_hours, t.stale_action, r.work_schedule
+		FROM pull_request_reviewers prr
+		JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id
+		JOIN users a ON a.user_id = pr.author_id
+		JOIN teams t ON t.team_name = a.team_name
+		JOIN users r ON r.user_id = prr.user_id
+		WHERE pr.status = 'OPEN'
+			AND prr.first_decision_at IS NULL
+			AND prr.escalated_at IS NULL
+			AND t.stale_after_hours IS NOT NULL
+		ORDER BY prr.assigned_at
+	`)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	var stale []*models.StaleReview
 	for rows.Next() {
-		var member models.TeamMember
-		if err := rows.Scan(&member.UserID, &member.Username, &member.IsActive); err != nil {
+		var review models.StaleReview
+		var schedule []byte
+		if err := rows.Scan(&review.PullRequestID, &review.AuthorID, &review.TeamName, &review.ReviewerID,
+			&review.AssignedAt, &review.StaleAfterHours, &review.Action, &schedule); err != nil {
 			return nil, err
 		}
-		team.Members = append(team.Members, member)
+		if review.ReviewerSchedule, err = decodeSchedule(schedule); err != nil {
+			return nil, err
+		}
+		stale = append(stale, &review)
 	}
 
-	if len(team.Members) == 0 {
-		return nil, errors.New("NOT_FOUND")
-	}
+	return stale, rows.Err()
+}
 
-	return &team, nil
+// MarkReviewEscalated records that the stale review was acted on so the
+// scheduler does not pick it up again.
+func (s *PostgresStore) MarkReviewEscalated(ctx context.Context, prID, userID string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, `
+		UPDATE pull_request_reviewers
+		SET escalated_at = NOW()
+		WHERE pull_request_id = $1 AND user_id = $2
+	`, prID, userID)
+	return err
 }
 
-func (s *PostgresStore) UpdateUserActive(userID string, isActive bool) (*models.User, error) {
-	var user models.User
-	err := s.db.QueryRow(`
-		UPDATE users 
-		SET is_active = $1, updated_at = NOW() 
-		WHERE user_id = $2
-		RETURNING user_id, username, team_name, is_active
-	`, isActive, userID).Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive)
+// AddBackupReviewer assigns a reviewer picked by choose next to the stale
+// reviewer on an OPEN PR and marks the stale review as escalated. The
+// candidates are those a reassign would offer, with the stale reviewer kept,
+// and a PR already at its team's max_reviewers gets no backup.
+func (s *PostgresStore) AddBackupReviewer(ctx context.Context, prID, staleUserID string, choose ReviewerChooser) (string, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.New("NOT_FOUND")
-		}
-		return nil, err
+		return "", err
 	}
+	defer tx.Rollback()
 
-	return &user, nil
-}
+	pr, err := getPR(ctx, tx, prID, true)
+	if err != nil {
+		return "", err
+	}
 
-func (s *PostgresStore) GetUser(userID string) (*models.User, error) {
-	var user models.User
-	err := s.db.QueryRow(`
-		SELECT user_id, username, team_name, is_active 
+	if pr.Status == "MERGED" {
+		return "", errors.New("PR_MERGED")
+	}
+	assigned := false
+	for _, reviewerID := range pr.AssignedReviewers {
+		assigned = assigned || reviewerID == staleUserID
+	}
+	if !assigned {
+		return "", errors.New("NOT_ASSIGNED")
+	}
+
+	var maxReviewers int
+	err = tx.QueryRowContext(ctx, `
+		SELECT t.max_reviewers
+		FROM users a
+		JOIN teams t ON t.team_name = a.team_name
+		WHERE a.user_id = $1
+	`, pr.AuthorID).Scan(&maxReviewers)
+	if err != nil {
+		return "", err
+	}
+	if len(pr.AssignedReviewers) >= maxReviewers {
+		return "", errors.New("TOO_MANY_REVIEWERS")
+	}
+
+	staleUser, err := scanUser(tx.QueryRowContext(ctx, `
+		SELECT `+userColumns+` 
 		FROM users 
 		WHERE user_id = $1
-	`, userID).Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive)
+	`, staleUserID))
+	if err != nil {
+		return "", err
+	}
+
+	choice, err := loadReviewerChoice(ctx, tx, pr, staleUser, staleUser.TeamName)
+	if err != nil {
+		return "", err
+	}
+	choice.Kept = append(choice.Kept, staleUser)
+
+	backupUserID, err := choose(choice)
+	if err != nil {
+		return "", err
+	}
+
+	if err := assignReviewer(ctx, tx, prID, backupUserID, models.AssignReasonEscalation); err != nil {
+		return "", err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE pull_request_reviewers
+		SET escalated_at = NOW()
+		WHERE pull_request_id = $1 AND user_id = $2
+	`, prID, staleUserID)
+	if err != nil {
+		return "", err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return "", err
+	}
+
+	return backupUserID, nil
+}
+
+// TryAdvisoryLock takes the Postgres session advisory lock key on a
+// dedicated connection without waiting. When acquired, the returned unlock
+// releases the lock and the connection.
+func (s *PostgresStore) TryAdvisoryLock(ctx context.Context, key int64) (func() error, bool, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, err
+	}
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key)
+		return err
+	}
+	return unlock, true, nil
+}
+
+// RecordReviewDecision stores a reviewer's latest decision on an OPEN PR.
+// The time of the first decision is kept so review latency can be measured.
+func (s *PostgresStore) RecordReviewDecision(ctx context.Context, prID, userID, decision string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
+	var status string
+	err = tx.QueryRowContext(ctx, `
+		SELECT status FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE
+	`, prID).Scan(&status)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("NOT_FOUND")
+			return errors.New("NOT_FOUND")
 		}
-		return nil, err
+		return err
 	}
 
-	return &user, nil
+	if status == "MERGED" {
+		return errors.New("PR_MERGED")
+	}
+
+	result, err := tx.ExecContext(ctx, `
+		UPDATE pull_request_reviewers
+		SET decision = $3, first_decision_at = COALESCE(first_decision_at, NOW())
+		WHERE pull_request_id = $1 AND user_id = $2
+	`, prID, userID, decision)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("NOT_ASSIGNED")
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE reviewer_assignments
+		SET decision = $3, first_decision_at = COALESCE(first_decision_at, NOW())
+		WHERE pull_request_id = $1 AND user_id = $2 AND unassigned_at IS NULL
+	`, prID, userID, decision)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (s *PostgresStore) GetActiveTeamMembers(teamName string, excludeUserID string) ([]*models.User, error) {
-	rows, err := s.db.Query(`
-		SELECT user_id, username, team_name, is_active 
-		FROM users 
-		WHERE team_name = $1 AND is_active = true AND user_id != $2
-		ORDER BY user_id
-	`, teamName, excludeUserID)
+// GetPendingReviews lists reviewers of OPEN PRs who have not made a decision
+// yet, for teams that have a review SLA. An empty teamName means all teams.
+func (s *PostgresStore) GetPendingReviews(ctx context.Context, teamName string) ([]*models.PendingReview, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, t.team_name,
+			prr.user_id, prr.assigned_at, t.review_sla_hours, r.work_schedule
+		FROM pull_request_reviewers prr
+		JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id
+		JOIN users a ON a.user_id = pr.author_id
+		JOIN teams t ON t.team_name = a.team_name
+		JOIN users r ON r.user_id = prr.user_id
+		WHERE pr.status = 'OPEN'
+			AND prr.first_decision_at IS NULL
+			AND t.review_sla_hours IS NOT NULL
+			AND ($1 = '' OR t.team_name = $1)
+		ORDER BY prr.assigned_at
+	`, teamName)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var users []*models.User
+	var pending []*models.PendingReview
 	for rows.Next() {
-		var user models.User
-		if err := rows.Scan(&user.UserID, &user.Username, &user.TeamName, &user.IsActive); err != nil {
+		var review models.PendingReview
+		var schedule []byte
+		if err := rows.Scan(&review.PullRequestID, &review.PullRequestName, &review.AuthorID, &review.TeamName,
+			&review.ReviewerID, &review.AssignedAt, &review.SLAHours, &schedule); err != nil {
 			return nil, err
 		}
-		users = append(users, &user)
+		if review.ReviewerSchedule, err = decodeSchedule(schedule); err != nil {
+			return nil, err
+		}
+		pending = append(pending, &review)
 	}
 
-	return users, nil
+	return pending, rows.Err()
 }
 
-func (s *PostgresStore) CreatePR(pr *models.PullRequest) error {
-	tx, err := s.db.Begin()
+// AddPRReviewer assigns userID to an OPEN PR. The PR row is locked for the
+// duration of the checks so concurrent changes cannot exceed the author
+// team's max_reviewers.
+func (s *PostgresStore) AddPRReviewer(ctx context.Context, prID, userID string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	var exists bool
-	err = tx.QueryRow("SELECT EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id = $1)", pr.PullRequestID).Scan(&exists)
+	var status, authorID string
+	var maxReviewers int
+	err = tx.QueryRowContext(ctx, `
+		SELECT pr.status, pr.author_id, t.max_reviewers
+		FROM pull_requests pr
+		JOIN users a ON a.user_id = pr.author_id
+		JOIN teams t ON t.team_name = a.team_name
+		WHERE pr.pull_request_id = $1
+		FOR UPDATE OF pr
+	`, prID).Scan(&status, &authorID, &maxReviewers)
 	if err != nil {
+		if err == sql.ErrNoRows {
+			return errors.New("NOT_FOUND")
+		}
 		return err
 	}
-	if exists {
-		return fmt.Errorf("PR_EXISTS")
+
+	if status == "MERGED" {
+		return errors.New("PR_MERGED")
+	}
+	if authorID == userID {
+		return errors.New("IS_AUTHOR")
 	}
 
-	var authorTeam string
-	err = tx.QueryRow("SELECT team_name FROM users WHERE user_id = $1", pr.AuthorID).Scan(&authorTeam)
+	var available bool
+	err = tx.QueryRowContext(ctx, `
+		SELECT is_active AND (leave_until IS NULL OR leave_until <= NOW())
+		FROM users
+		WHERE user_id = $1
+	`, userID).Scan(&available)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errors.New("NOT_FOUND")
 		}
 		return err
 	}
+	if !available {
+		return errors.New("USER_INACTIVE")
+	}
 
-	_, err = tx.Exec(`
-		INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status)
-		VALUES ($1, $2, $3, 'OPEN')
-	`, pr.PullRequestID, pr.PullRequestName, pr.AuthorID)
+	var assigned bool
+	var count int
+	err = tx.QueryRowContext(ctx, `
+		SELECT COALESCE(BOOL_OR(user_id = $2), false), COUNT(*)
+		FROM pull_request_reviewers
+		WHERE pull_request_id = $1
+	`, prID, userID).Scan(&assigned, &count)
 	if err != nil {
 		return err
 	}
+	if assigned {
+		return errors.New("ALREADY_ASSIGNED")
+	}
+	if count >= maxReviewers {
+		return errors.New("TOO_MANY_REVIEWERS")
+	}
 
-	for _, reviewerID := range pr.AssignedReviewers {
-		_, err = tx.Exec(`
-			INSERT INTO pull_request_reviewers (pull_request_id, user_id)
-			VALUES ($1, $2)
-		`, pr.PullRequestID, reviewerID)
-		if err != nil {
-			return err
-		}
+	if err := assignReviewer(ctx, tx, prID, userID, models.AssignReasonManual); err != nil {
+		return err
 	}
 
 	return tx.Commit()
 }
 
-func (s *PostgresStore) GetPR(prID string) (*models.PullRequest, error) {
-	var pr models.PullRequest
-	var createdAt, mergedAt sql.NullTime
+func (s *PostgresStore) RemovePRReviewer(ctx context.Context, prID, userID string) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
 
-	err := s.db.QueryRow(`
-		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status, pr.created_at, pr.merged_at
-		FROM pull_requests pr
-		WHERE pr.pull_request_id = $1
-	`, prID).Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, &createdAt, &mergedAt)
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
 
+	var status string
+	err = tx.QueryRowContext(ctx, `
+		SELECT status FROM pull_requests WHERE pull_request_id = $1 FOR UPDATE
+	`, prID).Scan(&status)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.New("NOT_FOUND")
+			return errors.New("NOT_FOUND")
 		}
-		return nil, err
+		return err
 	}
 
-	if createdAt.Valid {
-		pr.CreatedAt = &createdAt.Time
+	if status == "MERGED" {
+		return errors.New("PR_MERGED")
 	}
-	if mergedAt.Valid {
-		pr.MergedAt = &mergedAt.Time
+
+	if err := unassignReviewer(ctx, tx, prID, userID, models.AssignReasonManual); err != nil {
+		return err
 	}
 
-	rows, err := s.db.Query(`
-		SELECT user_id 
-		FROM pull_request_reviewers 
-		WHERE pull_request_id = $1
-		ORDER BY assigned_at
-	`, prID)
+	return tx.Commit()
+}
+
+// prSortColumns maps PRListFilter.SortBy to a column and the SQL type used
+// to compare it with the cursor value.
+var prSortColumns = map[string][2]string{
+	"created_at":        {"pr.created_at", "timestamp"},
+	"pull_request_name": {"pr.pull_request_name", "text"},
+}
+
+// ListPRs returns up to filter.Limit PRs matching filter, with reviewers,
+// using keyset pagination on (sort column, pull_request_id).
+func (s *PostgresStore) ListPRs(ctx context.Context, filter models.PRListFilter) ([]*models.PullRequest, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	sortColumn, ok := prSortColumns[filter.SortBy]
+	if !ok {
+		return nil, errors.New("INVALID_FILTER")
+	}
+
+	var conditions []string
+	var args []interface{}
+	arg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.Status != "" {
+		conditions = append(conditions, "pr.status = "+arg(filter.Status))
+	}
+	if filter.AuthorID != "" {
+		conditions = append(conditions, "pr.author_id = "+arg(filter.AuthorID))
+	}
+	if filter.ReviewerID != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM pull_request_reviewers prr
+			WHERE prr.pull_request_id = pr.pull_request_id AND prr.user_id = `+arg(filter.ReviewerID)+`)`)
+	}
+	if filter.TeamName != "" {
+		conditions = append(conditions, "a.team_name = "+arg(filter.TeamName))
+	}
+	if filter.NameQuery != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(filter.NameQuery)
+		conditions = append(conditions, "pr.pull_request_name ILIKE "+arg("%"+escaped+"%"))
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "pr.created_at >= "+arg(filter.CreatedFrom.UTC()))
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "pr.created_at < "+arg(filter.CreatedTo.UTC()))
+	}
+
+	// NULL sort values come last in both directions, so a page ending on a
+	// non-NULL value continues with the larger (or smaller) values and then
+	// all NULLs, and a page ending on a NULL only with NULLs.
+	direction, comparison := "ASC", ">"
+	if filter.Descending {
+		direction, comparison = "DESC", "<"
+	}
+	if filter.AfterID != "" {
+		if filter.AfterNull {
+			conditions = append(conditions, fmt.Sprintf("(%s IS NULL AND pr.pull_request_id %s %s)",
+				sortColumn[0], comparison, arg(filter.AfterID)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("(%s IS NULL OR (%s, pr.pull_request_id) %s (%s::%s, %s))",
+				sortColumn[0], sortColumn[0], comparison, arg(filter.AfterValue), sortColumn[1], arg(filter.AfterID)))
+		}
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = "WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`
+		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status, pr.labels, pr.created_at, pr.merged_at,
+			ARRAY(
+				SELECT prr.user_id FROM pull_request_reviewers prr
+				WHERE prr.pull_request_id = pr.pull_request_id
+				ORDER BY prr.assigned_at
+			)
+		FROM pull_requests pr
+		JOIN users a ON a.user_id = pr.author_id
+		%s
+		ORDER BY %s %s NULLS LAST, pr.pull_request_id %s
+		LIMIT %s
+	`, where, sortColumn[0], direction, direction, arg(filter.Limit)), args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
+	prs := make([]*models.PullRequest, 0)
 	for rows.Next() {
-		var reviewerID string
-		if err := rows.Scan(&reviewerID); err != nil {
+		var pr models.PullRequest
+		var createdAt, mergedAt sql.NullTime
+		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status, pq.Array(&pr.Labels),
+			&createdAt, &mergedAt, pq.Array(&pr.AssignedReviewers)); err != nil {
 			return nil, err
 		}
-		pr.AssignedReviewers = append(pr.AssignedReviewers, reviewerID)
+		if createdAt.Valid {
+			pr.CreatedAt = &createdAt.Time
+		}
+		if mergedAt.Valid {
+			pr.MergedAt = &mergedAt.Time
+		}
+		prs = append(prs, &pr)
 	}
 
-	return &pr, nil
+	return prs, rows.Err()
 }
 
-func (s *PostgresStore) MergePR(prID string) error {
-	_, err := s.db.Exec(`
-		UPDATE pull_requests 
-		SET status = 'MERGED', merged_at = NOW() 
-		WHERE pull_request_id = $1 AND status != 'MERGED'
-	`, prID)
-	return err
+func (s *PostgresStore) GetOpenReviewCounts(ctx context.Context, userIDs []string) (map[string]int, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return getOpenReviewCounts(ctx, s.db, userIDs)
 }
 
-func (s *PostgresStore) UpdatePRReviewers(prID string, reviewers []string) error {
-	tx, err := s.db.Begin()
-	if err != nil {
-		return err
+func getOpenReviewCounts(ctx context.Context, q querier, userIDs []string) (map[string]int, error) {
+	counts := make(map[string]int, len(userIDs))
+	if len(userIDs) == 0 {
+		return counts, nil
 	}
-	defer tx.Rollback()
 
-	_, err = tx.Exec("DELETE FROM pull_request_reviewers WHERE pull_request_id = $1", prID)
+	rows, err := q.QueryContext(ctx, `
+		SELECT prr.user_id, COUNT(*)
+		FROM pull_request_reviewers prr
+		JOIN pull_requests pr ON pr.pull_request_id = prr.pull_request_id
+		WHERE prr.user_id = ANY($1) AND pr.status = 'OPEN'
+		GROUP BY prr.user_id
+	`, pq.Array(userIDs))
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	for _, reviewerID := range reviewers {
-		_, err = tx.Exec(`
-			INSERT INTO pull_request_reviewers (pull_request_id, user_id)
-			VALUES ($1, $2)
-		`, prID, reviewerID)
-		if err != nil {
-			return err
+	for rows.Next() {
+		var userID string
+		var count int
+		if err := rows.Scan(&userID, &count); err != nil {
+			return nil, err
 		}
+		counts[userID] = count
 	}
 
-	return tx.Commit()
+	return counts, rows.Err()
 }
 
-func (s *PostgresStore) GetUserReviewPRs(userID string) ([]*models.PullRequestShort, error) {
-	rows, err := s.db.Query(`
-		SELECT pr.pull_request_id, pr.pull_request_name, pr.author_id, pr.status
-		FROM pull_requests pr
-		JOIN pull_request_reviewers prr ON pr.pull_request_id = prr.pull_request_id
-		WHERE prr.user_id = $1
-		ORDER BY pr.created_at DESC
-	`, userID)
+func (s *PostgresStore) CreateTeamRule(ctx context.Context, rule *models.TeamRule) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, `
+		INSERT INTO team_rules (team_name, rule_type, author_id, reviewer_id)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''))
+		RETURNING id, created_at
+	`, rule.TeamName, rule.Type, rule.AuthorID, rule.ReviewerID).Scan(&rule.ID, &rule.CreatedAt)
+	if err != nil {
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "foreign_key_violation" {
+			return errors.New("NOT_FOUND")
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *PostgresStore) GetTeamRules(ctx context.Context, teamName string) ([]*models.TeamRule, error) {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	return getTeamRules(ctx, s.db, teamName)
+}
+
+func getTeamRules(ctx context.Context, q querier, teamName string) ([]*models.TeamRule, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT id, team_name, rule_type, COALESCE(author_id, ''), COALESCE(reviewer_id, ''), created_at
+		FROM team_rules
+		WHERE team_name = $1
+		ORDER BY id
+	`, teamName)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var prs []*models.PullRequestShort
+	var rules []*models.TeamRule
 	for rows.Next() {
-		var pr models.PullRequestShort
-		if err := rows.Scan(&pr.PullRequestID, &pr.PullRequestName, &pr.AuthorID, &pr.Status); err != nil {
+		var rule models.TeamRule
+		if err := rows.Scan(&rule.ID, &rule.TeamName, &rule.Type, &rule.AuthorID, &rule.ReviewerID, &rule.CreatedAt); err != nil {
 			return nil, err
 		}
-		prs = append(prs, &pr)
+		rules = append(rules, &rule)
 	}
 
-	return prs, nil
+	return rules, rows.Err()
 }
 
-func (s *PostgresStore) IsUserAssignedToPR(prID, userID string) (bool, error) {
-	var exists bool
-	err := s.db.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM pull_request_reviewers 
-			WHERE pull_request_id = $1 AND user_id = $2
-		)
-	`, prID, userID).Scan(&exists)
-	return exists, err
+func (s *PostgresStore) DeleteTeamRule(ctx context.Context, ruleID int64) error {
+	ctx, cancel := s.withTimeout(ctx)
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM team_rules WHERE id = $1", ruleID)
+	if err != nil {
+		return err
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.New("NOT_FOUND")
+	}
+
+	return nil
 }
 
 func (s *PostgresStore) Close() error {
 	return s.db.Close()
 }
+
+// tagsOrEmpty keeps NOT NULL text[] columns from receiving a NULL array.
+func tagsOrEmpty(tags []string) []string {
+	if tags == nil {
+		return []string{}
+	}
+	return tags
+}
@@ -0,0 +1,259 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"pr-reviewer/internal/models"
+)
+
+func TestSyncTeamsKeepsTeamsWithOpenPRs(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	for _, team := range []*models.Team{
+		{TeamName: "backend", Members: []models.TeamMember{{UserID: "u1", Username: "u1", IsActive: true}}},
+		{TeamName: "payments", Members: []models.TeamMember{{UserID: "u2", Username: "u2", IsActive: true}}},
+	} {
+		if err := s.CreateTeam(ctx, team); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.CreatePR(ctx, &models.PullRequest{PullRequestID: "pr-1", PullRequestName: "open", AuthorID: "u2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	keepBackend := []models.Team{{TeamName: "backend", Members: []models.TeamMember{{UserID: "u1", Username: "u1", IsActive: true}}}}
+
+	diff, err := s.SyncTeams(ctx, keepBackend, true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.DeletedTeams) != 0 || len(diff.BlockedTeams) != 1 || diff.BlockedTeams[0] != "payments" {
+		t.Fatalf("deleted %v, blocked %v; want payments blocked", diff.DeletedTeams, diff.BlockedTeams)
+	}
+
+	if _, err := s.SyncTeams(ctx, keepBackend, false, nil); err == nil || err.Error() != "TEAM_HAS_OPEN_PRS" {
+		t.Fatalf("err = %v, want TEAM_HAS_OPEN_PRS", err)
+	}
+	if _, err := s.GetTeam(ctx, "payments"); err != nil {
+		t.Fatalf("payments after the rejected sync: %v", err)
+	}
+
+	if err := s.MergePR(ctx, "pr-1"); err != nil {
+		t.Fatal(err)
+	}
+	diff, err = s.SyncTeams(ctx, keepBackend, false, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.DeletedTeams) != 1 || diff.DeletedTeams[0] != "payments" || len(diff.BlockedTeams) != 0 {
+		t.Fatalf("deleted %v, blocked %v; want payments deleted", diff.DeletedTeams, diff.BlockedTeams)
+	}
+}
+
+// createTeams creates teams whose members are active users named after
+// their ids.
+func createTeams(t *testing.T, s *PostgresStore, members map[string][]string) {
+	t.Helper()
+	for teamName, userIDs := range members {
+		team := &models.Team{TeamName: teamName}
+		for _, userID := range userIDs {
+			team.Members = append(team.Members, models.TeamMember{UserID: userID, Username: userID, IsActive: true})
+		}
+		if err := s.CreateTeam(context.Background(), team); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func createPR(t *testing.T, s *PostgresStore, prID, authorID string, reviewers ...string) {
+	t.Helper()
+	pr := &models.PullRequest{PullRequestID: prID, PullRequestName: prID, AuthorID: authorID, AssignedReviewers: reviewers}
+	if err := s.CreatePR(context.Background(), pr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// pick returns a chooser that hands the review to userID, failing when the
+// store did not offer userID as a candidate.
+func pick(userID string) ReviewerChooser {
+	return func(choice *ReviewerChoice) (string, error) {
+		for _, candidate := range choice.Candidates {
+			if candidate.UserID == userID {
+				return userID, nil
+			}
+		}
+		return "", errors.New("NOT_A_CANDIDATE")
+	}
+}
+
+func assertReviewers(t *testing.T, s *PostgresStore, prID string, want ...string) {
+	t.Helper()
+	pr, err := s.GetPR(context.Background(), prID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(pr.AssignedReviewers, want) {
+		t.Errorf("%s reviewers = %v, want %v", prID, pr.AssignedReviewers, want)
+	}
+}
+
+func TestRemoveTeamMemberHandsOffReviews(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	createTeams(t, s, map[string][]string{"backend": {"u1", "u2", "u3"}, "payments": {"p1"}})
+	createPR(t, s, "pr-1", "u1", "u2")
+	if err := s.CreateTeamRule(ctx, &models.TeamRule{TeamName: "backend", Type: "exclude", AuthorID: "u1", ReviewerID: "u2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.RemoveTeamMember(ctx, "payments", "u2", pick("p1")); err == nil || err.Error() != "NOT_TEAM_MEMBER" {
+		t.Fatalf("err = %v, want NOT_TEAM_MEMBER", err)
+	}
+
+	handoffs, err := s.RemoveTeamMember(ctx, "backend", "u2", pick("u3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*models.ReviewHandoff{{PullRequestID: "pr-1", OldReviewerID: "u2", NewReviewerID: "u3"}}
+	if !reflect.DeepEqual(handoffs, want) {
+		t.Fatalf("handoffs = %+v, want %+v", handoffs, want)
+	}
+	assertReviewers(t, s, "pr-1", "u3")
+
+	user, err := s.GetUser(ctx, "u2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.TeamName != "" || user.IsActive {
+		t.Errorf("u2 = %+v, want no team and inactive", user)
+	}
+	rules, err := s.GetTeamRules(ctx, "backend")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 0 {
+		t.Errorf("rules = %+v, want the rule naming u2 deleted", rules)
+	}
+}
+
+func TestMoveTeamMemberHandsOffOnlyWithChooser(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	createTeams(t, s, map[string][]string{"backend": {"u1", "u2", "u3", "u4"}, "payments": {"p1"}})
+	createPR(t, s, "pr-1", "u1", "u2")
+	createPR(t, s, "pr-2", "u1", "u3")
+
+	handoffs, err := s.MoveTeamMember(ctx, "u2", "payments", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handoffs) != 0 {
+		t.Errorf("handoffs without a chooser = %+v, want none", handoffs)
+	}
+	assertReviewers(t, s, "pr-1", "u2")
+
+	handoffs, err = s.MoveTeamMember(ctx, "u3", "payments", pick("u4"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*models.ReviewHandoff{{PullRequestID: "pr-2", OldReviewerID: "u3", NewReviewerID: "u4"}}
+	if !reflect.DeepEqual(handoffs, want) {
+		t.Fatalf("handoffs = %+v, want %+v", handoffs, want)
+	}
+	assertReviewers(t, s, "pr-2", "u4")
+
+	user, err := s.GetUser(ctx, "u3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.TeamName != "payments" || !user.IsActive {
+		t.Errorf("u3 = %+v, want an active member of payments", user)
+	}
+
+	handoffs, err = s.MoveTeamMember(ctx, "u4", "backend", pick("u1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(handoffs) != 0 {
+		t.Errorf("handoffs moving into the current team = %+v, want none", handoffs)
+	}
+	assertReviewers(t, s, "pr-2", "u4")
+}
+
+func TestRenameTeamKeepsMembersRulesAndPRs(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	createTeams(t, s, map[string][]string{"backend": {"u1", "u2"}, "payments": {"p1"}})
+	createPR(t, s, "pr-1", "u1", "u2")
+	if err := s.CreateTeamRule(ctx, &models.TeamRule{TeamName: "backend", Type: "require_senior"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.RenameTeam(ctx, "backend", "payments"); err == nil || err.Error() != "TEAM_EXISTS" {
+		t.Fatalf("err = %v, want TEAM_EXISTS", err)
+	}
+	if err := s.RenameTeam(ctx, "backend", "platform"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.GetTeam(ctx, "backend"); err == nil {
+		t.Error("the old team name still resolves")
+	}
+	members, err := s.GetTeamUsers(ctx, "platform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(members) != 2 {
+		t.Errorf("platform members = %d, want 2", len(members))
+	}
+	rules, err := s.GetTeamRules(ctx, "platform")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 1 || rules[0].TeamName != "platform" {
+		t.Errorf("rules = %+v, want the rule moved to platform", rules)
+	}
+	assertReviewers(t, s, "pr-1", "u2")
+}
+
+func TestDeleteTeamHandsOffReviewsOfOtherTeams(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+	createTeams(t, s, map[string][]string{"backend": {"u1", "u2"}, "payments": {"p1", "p2"}})
+	createPR(t, s, "pr-1", "u1", "p1")
+	createPR(t, s, "pr-2", "p2")
+
+	if _, err := s.DeleteTeam(ctx, "payments", pick("u2")); err == nil || err.Error() != "TEAM_HAS_OPEN_PRS" {
+		t.Fatalf("err = %v, want TEAM_HAS_OPEN_PRS while p2 authors an OPEN PR", err)
+	}
+	if err := s.MergePR(ctx, "pr-2"); err != nil {
+		t.Fatal(err)
+	}
+
+	handoffs, err := s.DeleteTeam(ctx, "payments", pick("u2"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*models.ReviewHandoff{{PullRequestID: "pr-1", OldReviewerID: "p1", NewReviewerID: "u2"}}
+	if !reflect.DeepEqual(handoffs, want) {
+		t.Fatalf("handoffs = %+v, want %+v", handoffs, want)
+	}
+	assertReviewers(t, s, "pr-1", "u2")
+
+	if _, err := s.GetTeam(ctx, "payments"); err == nil {
+		t.Error("payments still exists")
+	}
+	for _, userID := range []string{"p1", "p2"} {
+		user, err := s.GetUser(ctx, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.TeamName != "" || user.IsActive {
+			t.Errorf("%s = %+v, want no team and inactive", userID, user)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+
+	"pr-reviewer/internal/tracing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedDB and tracedTx emit a span for every SQL statement. They satisfy
+// querier, so the read and write helpers are traced in and outside
+// transactions alike.
+type tracedDB struct {
+	*sql.DB
+}
+
+type tracedTx struct {
+	*sql.Tx
+}
+
+func (db *tracedDB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*tracedTx, error) {
+	ctx, span := startQuerySpan(ctx, "BEGIN")
+	defer span.End()
+
+	tx, err := db.DB.BeginTx(ctx, opts)
+	if err != nil {
+		tracing.Fail(span, err)
+		return nil, err
+	}
+	return &tracedTx{Tx: tx}, nil
+}
+
+func (db *tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*tracedRows, error) {
+	return tracedQuery(ctx, db.DB, query, args...)
+}
+
+func (db *tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tracedQueryRow(ctx, db.DB, query, args...)
+}
+
+func (db *tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tracedExec(ctx, db.DB, query, args...)
+}
+
+func (tx *tracedTx) QueryContext(ctx context.Context, query string, args ...interface{}) (*tracedRows, error) {
+	return tracedQuery(ctx, tx.Tx, query, args...)
+}
+
+func (tx *tracedTx) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return tracedQueryRow(ctx, tx.Tx, query, args...)
+}
+
+func (tx *tracedTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return tracedExec(ctx, tx.Tx, query, args...)
+}
+
+// sqlConn is the part of *sql.DB and *sql.Tx that tracedDB and tracedTx
+// wrap.
+type sqlConn interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// tracedRows keeps the query span open while the rows are read, as
+// PostgreSQL streams them, and ends it when they are exhausted or closed.
+type tracedRows struct {
+	*sql.Rows
+	span trace.Span
+	once sync.Once
+}
+
+func (r *tracedRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	r.end()
+	return false
+}
+
+func (r *tracedRows) Close() error {
+	err := r.Rows.Close()
+	r.end()
+	return err
+}
+
+func (r *tracedRows) end() {
+	r.once.Do(func() {
+		if err := r.Rows.Err(); err != nil {
+			tracing.Fail(r.span, err)
+		}
+		r.span.End()
+	})
+}
+
+func tracedQuery(ctx context.Context, q sqlConn, query string, args ...interface{}) (*tracedRows, error) {
+	ctx, span := startQuerySpan(ctx, query)
+
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		tracing.Fail(span, err)
+		span.End()
+		return nil, err
+	}
+	return &tracedRows{Rows: rows, span: span}, nil
+}
+
+func tracedQueryRow(ctx context.Context, q sqlConn, query string, args ...interface{}) *sql.Row {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	row := q.QueryRowContext(ctx, query, args...)
+	if err := row.Err(); err != nil {
+		tracing.Fail(span, err)
+	}
+	return row
+}
+
+func tracedExec(ctx context.Context, q sqlConn, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := startQuerySpan(ctx, query)
+	defer span.End()
+
+	result, err := q.ExecContext(ctx, query, args...)
+	if err != nil {
+		tracing.Fail(span, err)
+	}
+	return result, err
+}
+
+// startQuerySpan names the span after the statement's leading keyword and
+// records the statement text without its arguments.
+func startQuerySpan(ctx context.Context, query string) (context.Context, trace.Span) {
+	statement := strings.Join(strings.Fields(query), " ")
+	operation := statement
+	if i := strings.IndexByte(statement, ' '); i > 0 {
+		operation = statement[:i]
+	}
+	return tracing.StartClient(ctx, "db "+strings.ToUpper(operation),
+		attribute.String("db.system", "postgresql"),
+		attribute.String("db.statement", statement),
+	)
+}
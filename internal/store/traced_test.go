@@ -0,0 +1,167 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() {
+		provider.Shutdown(context.Background())
+		otel.SetTracerProvider(previous)
+	})
+	return recorder
+}
+
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) (string, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.AsString(), true
+		}
+	}
+	return "", false
+}
+
+func TestQuerySpanRecordsStatementAndError(t *testing.T) {
+	recorder := recordSpans(t)
+	var observed string
+	s := Instrument(unreachableStore(t, 0), func(ctx context.Context, method string, duration time.Duration, err error) {
+		observed = method
+	})
+
+	if _, err := s.GetPR(context.Background(), "pr-1"); err == nil {
+		t.Fatal("GetPR succeeded without a database")
+	}
+	if observed != "GetPR" {
+		t.Errorf("observed %q, want GetPR", observed)
+	}
+
+	var method, query sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		switch {
+		case span.Name() == "store.GetPR":
+			method = span
+		case strings.HasPrefix(span.Name(), "db "):
+			if query == nil {
+				query = span
+			}
+		}
+	}
+	if method == nil || query == nil {
+		t.Fatalf("spans = %v, want store.GetPR and a db span", recorder.Ended())
+	}
+
+	if query.Name() != "db SELECT" {
+		t.Errorf("query span = %q, want db SELECT", query.Name())
+	}
+	if query.Parent().SpanID() != method.SpanContext().SpanID() {
+		t.Errorf("query span parent = %s, want the store.GetPR span %s", query.Parent().SpanID(), method.SpanContext().SpanID())
+	}
+	statement, ok := attributeValue(query, "db.statement")
+	if !ok || !strings.HasPrefix(statement, "SELECT ") || strings.ContainsAny(statement, "\n\t") {
+		t.Errorf("db.statement = %q, want the statement on one line", statement)
+	}
+	if system, _ := attributeValue(query, "db.system"); system != "postgresql" {
+		t.Errorf("db.system = %q, want postgresql", system)
+	}
+	for _, span := range []sdktrace.ReadOnlySpan{query, method} {
+		if span.Status().Code != codes.Error {
+			t.Errorf("%s status = %v, want Error", span.Name(), span.Status().Code)
+		}
+	}
+}
+
+// rowsDriver serves every query with the ids 1 to 3, so tracedRows can be
+// tested without a database.
+type rowsDriver struct{}
+
+func (rowsDriver) Open(name string) (driver.Conn, error) { return rowsConn{}, nil }
+
+type rowsConn struct{}
+
+func (rowsConn) Prepare(query string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (rowsConn) Close() error                              { return nil }
+func (rowsConn) Begin() (driver.Tx, error)                 { return nil, driver.ErrSkip }
+
+func (rowsConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return &idRows{}, nil
+}
+
+type idRows struct{ next int64 }
+
+func (r *idRows) Columns() []string { return []string{"id"} }
+func (r *idRows) Close() error      { return nil }
+
+func (r *idRows) Next(dest []driver.Value) error {
+	if r.next == 3 {
+		return io.EOF
+	}
+	r.next++
+	dest[0] = r.next
+	return nil
+}
+
+func init() {
+	sql.Register("rows-test", rowsDriver{})
+}
+
+func TestQuerySpanCoversRowIteration(t *testing.T) {
+	recorder := recordSpans(t)
+	raw, err := sql.Open("rows-test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { raw.Close() })
+	db := &tracedDB{DB: raw}
+
+	rows, err := db.QueryContext(context.Background(), "SELECT id FROM pull_requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rows.Next() {
+		t.Fatal("no rows")
+	}
+	if ended := len(recorder.Ended()); ended != 0 {
+		t.Fatalf("%d spans ended while the rows were read, want none", ended)
+	}
+	rows.Close()
+	rows.Close()
+	if ended := len(recorder.Ended()); ended != 1 {
+		t.Fatalf("%d spans ended after Close, want one", ended)
+	}
+
+	rows, err = db.QueryContext(context.Background(), "SELECT id FROM pull_requests")
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	for rows.Next() {
+		count++
+	}
+	if count != 3 {
+		t.Errorf("read %d rows, want 3", count)
+	}
+	if ended := len(recorder.Ended()); ended != 2 {
+		t.Errorf("%d spans ended after the last row, want two", ended)
+	}
+	rows.Close()
+	if ended := len(recorder.Ended()); ended != 2 {
+		t.Errorf("%d spans ended after closing exhausted rows, want two", ended)
+	}
+}
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/models"
+)
+
+func TestUpdateUserLeaveKeepsInstant(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	team := &models.Team{TeamName: "backend", Members: []models.TeamMember{{UserID: "u1", Username: "u1", IsActive: true}}}
+	if err := s.CreateTeam(ctx, team); err != nil {
+		t.Fatal(err)
+	}
+
+	until := time.Date(2030, 5, 1, 12, 0, 0, 0, time.FixedZone("MSK", 3*60*60))
+	user, err := s.UpdateUserLeave(ctx, "u1", &until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.LeaveUntil == nil || !user.LeaveUntil.Equal(until) {
+		t.Fatalf("leave_until = %v, want %v", user.LeaveUntil, until)
+	}
+}
+
+func TestDeactivationHandsOffReviews(t *testing.T) {
+	s := openTestStore(t)
+	ctx := context.Background()
+
+	team := &models.Team{TeamName: "backend", Members: []models.TeamMember{
+		{UserID: "u1", Username: "u1", IsActive: true},
+		{UserID: "u2", Username: "u2", IsActive: true},
+		{UserID: "u3", Username: "u3", IsActive: true},
+	}}
+	if err := s.CreateTeam(ctx, team); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreatePR(ctx, &models.PullRequest{PullRequestID: "pr-1", PullRequestName: "pr", AuthorID: "u1", AssignedReviewers: []string{"u2"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	first := func(choice *ReviewerChoice) (string, error) {
+		return choice.Candidates[0].UserID, nil
+	}
+	user, handoffs, err := s.UpdateUserActive(ctx, "u2", false, first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.IsActive || len(handoffs) != 1 || handoffs[0].NewReviewerID != "u3" {
+		t.Fatalf("user %+v, handoffs %+v; want u2 inactive and replaced by u3", user, handoffs)
+	}
+
+	history, err := s.GetPRHistory(ctx, "pr-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].UnassignReason != models.AssignReasonDeactivation || history[1].Reason != models.AssignReasonDeactivation {
+		t.Fatalf("history = %+v, want u2 unassigned and u3 assigned for deactivation", history)
+	}
+}
@@ -0,0 +1,2 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS skills TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS labels TEXT[] NOT NULL DEFAULT '{}';
@@ -0,0 +1,14 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS seniority VARCHAR(20) NOT NULL DEFAULT ''
+    CHECK (seniority IN ('', 'junior', 'middle', 'senior'));
+
+CREATE TABLE IF NOT EXISTS team_rules (
+    id SERIAL PRIMARY KEY,
+    team_name VARCHAR(100) NOT NULL REFERENCES teams(team_name) ON DELETE CASCADE,
+    rule_type VARCHAR(30) NOT NULL,
+    author_id VARCHAR(100) REFERENCES users(user_id) ON DELETE CASCADE,
+    reviewer_id VARCHAR(100) REFERENCES users(user_id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT NOW(),
+    CHECK (rule_type IN ('exclude', 'require_senior', 'pair_junior_senior'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_team_rules_team ON team_rules(team_name);
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS reviewer_assignments (
+    id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(100) NOT NULL REFERENCES pull_requests(pull_request_id) ON DELETE CASCADE,
+    user_id VARCHAR(100) NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    assigned_at TIMESTAMP NOT NULL DEFAULT NOW(),
+    unassigned_at TIMESTAMP,
+    reason VARCHAR(20) NOT NULL,
+    unassign_reason VARCHAR(20),
+    CHECK (reason IN ('initial', 'reassign', 'manual', 'deactivation')),
+    CHECK (unassign_reason IN ('initial', 'reassign', 'manual', 'deactivation'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_reviewer_assignments_pr ON reviewer_assignments(pull_request_id, assigned_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reviewer_assignments_open
+    ON reviewer_assignments(pull_request_id, user_id) WHERE unassigned_at IS NULL;
+
+INSERT INTO reviewer_assignments (pull_request_id, user_id, assigned_at, reason)
+SELECT prr.pull_request_id, prr.user_id, COALESCE(prr.assigned_at, NOW()), 'initial'
+FROM pull_request_reviewers prr
+WHERE NOT EXISTS (
+    SELECT 1 FROM reviewer_assignments ra
+    WHERE ra.pull_request_id = prr.pull_request_id AND ra.user_id = prr.user_id
+);
@@ -0,0 +1,12 @@
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS review_sla_hours INT CHECK (review_sla_hours > 0);
+
+ALTER TABLE pull_request_reviewers ADD COLUMN IF NOT EXISTS decision VARCHAR(20)
+    CHECK (decision IN ('approved', 'changes_requested', 'commented'));
+ALTER TABLE pull_request_reviewers ADD COLUMN IF NOT EXISTS first_decision_at TIMESTAMP;
+
+ALTER TABLE reviewer_assignments ADD COLUMN IF NOT EXISTS decision VARCHAR(20)
+    CHECK (decision IN ('approved', 'changes_requested', 'commented'));
+ALTER TABLE reviewer_assignments ADD COLUMN IF NOT EXISTS first_decision_at TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS idx_pr_reviewers_pending ON pull_request_reviewers(assigned_at)
+    WHERE first_decision_at IS NULL;
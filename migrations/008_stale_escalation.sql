@@ -0,0 +1,12 @@
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS stale_after_hours INT CHECK (stale_after_hours > 0);
+ALTER TABLE teams ADD COLUMN IF NOT EXISTS stale_action VARCHAR(20) NOT NULL DEFAULT 'reassign'
+    CHECK (stale_action IN ('reassign', 'add_backup'));
+
+ALTER TABLE pull_request_reviewers ADD COLUMN IF NOT EXISTS escalated_at TIMESTAMP;
+
+ALTER TABLE reviewer_assignments DROP CONSTRAINT IF EXISTS reviewer_assignments_reason_check;
+ALTER TABLE reviewer_assignments DROP CONSTRAINT IF EXISTS reviewer_assignments_unassign_reason_check;
+ALTER TABLE reviewer_assignments ADD CONSTRAINT reviewer_assignments_reason_check
+    CHECK (reason IN ('initial', 'reassign', 'manual', 'deactivation', 'escalation'));
+ALTER TABLE reviewer_assignments ADD CONSTRAINT reviewer_assignments_unassign_reason_check
+    CHECK (unassign_reason IN ('initial', 'reassign', 'manual', 'deactivation', 'escalation'));
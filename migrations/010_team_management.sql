@@ -0,0 +1,14 @@
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_team_name_fkey;
+ALTER TABLE users ADD CONSTRAINT users_team_name_fkey
+    FOREIGN KEY (team_name) REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE team_rules DROP CONSTRAINT IF EXISTS team_rules_team_name_fkey;
+ALTER TABLE team_rules ADD CONSTRAINT team_rules_team_name_fkey
+    FOREIGN KEY (team_name) REFERENCES teams(team_name) ON UPDATE CASCADE ON DELETE CASCADE;
+
+ALTER TABLE reviewer_assignments DROP CONSTRAINT IF EXISTS reviewer_assignments_reason_check;
+ALTER TABLE reviewer_assignments DROP CONSTRAINT IF EXISTS reviewer_assignments_unassign_reason_check;
+ALTER TABLE reviewer_assignments ADD CONSTRAINT reviewer_assignments_reason_check
+    CHECK (reason IN ('initial', 'reassign', 'manual', 'deactivation', 'escalation', 'membership'));
+ALTER TABLE reviewer_assignments ADD CONSTRAINT reviewer_assignments_unassign_reason_check
+    CHECK (unassign_reason IN ('initial', 'reassign', 'manual', 'deactivation', 'escalation', 'membership'));
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS schema_migrations (
+    version INT PRIMARY KEY,
+    applied_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Migrations 001-010 predate this table and are recorded here once.
+INSERT INTO schema_migrations (version)
+SELECT generate_series(1, 10)
+ON CONFLICT (version) DO NOTHING;
+
+INSERT INTO schema_migrations (version) VALUES (11)
+ON CONFLICT (version) DO NOTHING;